@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/colour"
+	log "github.com/sirupsen/logrus"
+)
+
+// Credential authenticates downloads from a host, declared in config.hcl as:
+//
+//	credentials "github.com" {
+//	  token = "${env.GITHUB_TOKEN}"
+//	}
+//
+// A token is sent as a bearer token, otherwise Username and Password are sent using basic auth.
+// Either may reference environment variables as ${env.NAME}, so secrets don't have to be written
+// into config.hcl. Helper is a command that's run with the host as its last argument, and prints
+// the credentials as token=, username= and password= lines, like a git credential helper.
+//
+// Hosts without a credentials block fall back to ~/.netrc, or the file $NETRC points to.
+type Credential struct {
+	Host     string `hcl:"host,label"`
+	Token    string `hcl:"token,optional"`
+	Username string `hcl:"username,optional"`
+	Password string `hcl:"password,optional"`
+	Helper   string `hcl:"helper,optional"`
+}
+
+// Validate checks credentials declared in config.hcl, so mistakes are reported when the config is
+// loaded rather than on the first download from the host
+func (cred *Credential) Validate() error {
+	if cred.Helper != "" && strings.TrimSpace(cred.Helper) == "" {
+		return fmt.Errorf(colour.Sprintf("credentials for ^3%s^R have a blank helper", cred.Host))
+	}
+
+	if cred.Helper == "" && cred.Token == "" && cred.Username == "" && cred.Password == "" {
+		return fmt.Errorf(colour.Sprintf("credentials for ^3%s^R need a token, username and "+
+			"password, or helper", cred.Host))
+	}
+
+	return nil
+}
+
+var (
+	configured []*Credential
+
+	// resolved keeps the credentials of each host once found, so helpers only run once
+	resolved   = make(map[string]*Credential)
+	resolvedMu sync.Mutex
+)
+
+// Configure sets the credentials declared in config.hcl
+func Configure(credentials []*Credential) {
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+
+	configured = credentials
+	resolved = make(map[string]*Credential)
+}
+
+// Get makes a GET request to url with the credentials of its host, if any
+func Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Authorize(req); err != nil {
+		return nil, err
+	}
+
+	return http.DefaultClient.Do(req)
+}
+
+// Authorize adds the credentials of the request's host to it. The client only forwards them when
+// redirected within the same domain.
+func Authorize(req *http.Request) error {
+	cred, err := lookup(req.URL.Hostname())
+	if err != nil || cred == nil {
+		return err
+	}
+
+	if cred.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cred.Token)
+	} else if cred.Username != "" || cred.Password != "" {
+		req.SetBasicAuth(cred.Username, cred.Password)
+	}
+
+	return nil
+}
+
+// lookup finds the credentials for a host, or nil if it has none
+func lookup(host string) (*Credential, error) {
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+
+	if cred, ok := resolved[host]; ok {
+		return cred, nil
+	}
+
+	cred, err := resolve(host)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved[host] = cred
+	return cred, nil
+}
+
+func resolve(host string) (*Credential, error) {
+	for _, declared := range configured {
+		if !strings.EqualFold(declared.Host, host) {
+			continue
+		}
+
+		if declared.Helper != "" {
+			log.Debugf(colour.Sprintf("Using credential helper for ^3%s^R\n", host))
+			return runHelper(declared.Helper, host)
+		}
+
+		log.Debugf(colour.Sprintf("Using credentials from config.hcl for ^3%s^R\n", host))
+		return &Credential{
+			Host:     host,
+			Token:    expandEnv(declared.Token),
+			Username: expandEnv(declared.Username),
+			Password: expandEnv(declared.Password),
+		}, nil
+	}
+
+	cred, err := netrcCredential(host)
+	if err != nil {
+		return nil, err
+	} else if cred != nil {
+		log.Debugf(colour.Sprintf("Using credentials from netrc for ^3%s^R\n", host))
+	}
+
+	return cred, nil
+}
+
+var envPattern = regexp.MustCompile(`\$\{env\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${env.NAME} references with the value of the environment variable
+func expandEnv(value string) string {
+	return envPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envPattern.FindStringSubmatch(ref)[1]
+
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			log.Warnf(colour.Sprintf("Environment variable ^3%s^R referenced by credentials isn't "+
+				"set", name))
+		}
+
+		return env
+	})
+}
+
+// runHelper runs a credential helper for host. Its output holds secrets, so it's never logged or
+// included in errors.
+func runHelper(helper string, host string) (*Credential, error) {
+	parts := strings.Fields(helper)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf(colour.Sprintf("credential helper for ^3%s^R is blank", host))
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(parts[0], append(parts[1:], host)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf(colour.Sprintf("credential helper ^1%s^R failed for ^3%s^R: %s",
+			parts[0], host, err))
+	}
+
+	cred := &Credential{Host: host}
+
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		key, value, ok := cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "token":
+			cred.Token = value
+		case "username":
+			cred.Username = value
+		case "password":
+			cred.Password = value
+		}
+	}
+
+	if cred.Token == "" && cred.Username == "" && cred.Password == "" {
+		log.Warnf(colour.Sprintf("Credential helper ^3%s^R returned no credentials for ^3%s^R",
+			parts[0], host))
+		return nil, nil
+	}
+
+	return cred, nil
+}
+
+// cut splits s around the first instance of sep
+func cut(s string, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// helperScript writes a credential helper that prints output, with $1 replaced by the host
+func helperScript(t *testing.T, output string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("credential helpers are shell scripts")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "helper")
+	contents := "#!/bin/sh\necho run >> " + filepath.Join(dir, "runs") + "\n" + output
+	if err := os.WriteFile(script, []byte(contents), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return script
+}
+
+// helperRuns returns how many times the helper written by helperScript ran
+func helperRuns(t *testing.T, script string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(script), "runs"))
+	if os.IsNotExist(err) {
+		return 0
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Count(string(data), "run")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		cred Credential
+		ok   bool
+	}{
+		{Credential{Host: "github.com", Token: "${env.GITHUB_TOKEN}"}, true},
+		{Credential{Host: "example.com", Username: "user", Password: "pass"}, true},
+		{Credential{Host: "example.com", Helper: "pass-helper"}, true},
+		{Credential{Host: "example.com"}, false},
+		{Credential{Host: "example.com", Helper: "  "}, false},
+	}
+
+	for _, test := range tests {
+		err := test.cred.Validate()
+		if test.ok != (err == nil) {
+			t.Errorf("%+v: expected ok=%t, got error %v", test.cred, test.ok, err)
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("HVM_TEST_TOKEN", "s3cret")
+	defer os.Unsetenv("HVM_TEST_TOKEN")
+	os.Unsetenv("HVM_TEST_UNSET")
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"${env.HVM_TEST_TOKEN}", "s3cret"},
+		{"token-${env.HVM_TEST_TOKEN}-suffix", "token-s3cret-suffix"},
+		{"${env.HVM_TEST_UNSET}", ""},
+		{"$HVM_TEST_TOKEN", "$HVM_TEST_TOKEN"},
+		{"plain", "plain"},
+	}
+
+	for _, test := range tests {
+		if actual := expandEnv(test.value); actual != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.value, test.expected, actual)
+		}
+	}
+}
+
+func TestRunHelper(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected *Credential
+		ok       bool
+	}{
+		{"token", `echo "token=tok-$1"`, &Credential{Host: "example.com", Token: "tok-example.com"},
+			true},
+		{"basic auth", "echo username=user\necho 'password=pa=ss'\necho ignored",
+			&Credential{Host: "example.com", Username: "user", Password: "pa=ss"}, true},
+		{"nothing", "echo", nil, true},
+		{"failure", "exit 1", nil, false},
+	}
+
+	for _, test := range tests {
+		cred, err := runHelper(helperScript(t, test.output), "example.com")
+		if test.ok != (err == nil) {
+			t.Errorf("%s: expected ok=%t, got error %v", test.name, test.ok, err)
+			continue
+		}
+
+		if (cred == nil) != (test.expected == nil) ||
+			(cred != nil && *cred != *test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, cred)
+		}
+	}
+
+	if _, err := runHelper("  ", "example.com"); err == nil {
+		t.Error("expected a blank helper to fail")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	useNetrc(t, "machine netrc.example.com login netrc password fromfile\n")
+	os.Setenv("HVM_TEST_TOKEN", "s3cret")
+	defer os.Unsetenv("HVM_TEST_TOKEN")
+
+	helper := helperScript(t, "echo token=helped")
+	Configure([]*Credential{
+		{Host: "github.com", Token: "${env.HVM_TEST_TOKEN}"},
+		{Host: "basic.example.com", Username: "user", Password: "pass"},
+		{Host: "helper.example.com", Helper: helper},
+		{Host: "netrc.example.com", Token: "config wins"},
+	})
+	defer Configure(nil)
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://github.com/cli/cli/releases", "Bearer s3cret"},
+		{"https://GITHUB.COM/cli/cli/releases", "Bearer s3cret"},
+		{"https://basic.example.com:8443/tool", "Basic dXNlcjpwYXNz"},
+		{"https://helper.example.com/tool", "Bearer helped"},
+		{"https://helper.example.com/other", "Bearer helped"},
+		{"https://netrc.example.com/tool", "Bearer config wins"},
+		{"https://example.com/tool", ""},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Authorize(req); err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := req.Header.Get("Authorization"); actual != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.url, test.expected, actual)
+		}
+	}
+
+	if runs := helperRuns(t, helper); runs != 1 {
+		t.Errorf("expected the helper to run once, ran %d times", runs)
+	}
+
+	// Hosts without a credentials block fall back to netrc
+	Configure(nil)
+	req, err := http.NewRequest(http.MethodGet, "https://netrc.example.com/tool", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Authorize(req); err != nil {
+		t.Fatal(err)
+	}
+	if username, password, ok := req.BasicAuth(); !ok || username != "netrc" ||
+		password != "fromfile" {
+		t.Errorf("expected the credentials from netrc, got %s:%s", username, password)
+	}
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/josephschmitt/hvm/paths"
+)
+
+// netrcPath returns the path of the user's netrc file, which $NETRC overrides
+func netrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+
+	return filepath.Join(paths.AppPaths.HomeDirectory, ".netrc")
+}
+
+// netrcCredential returns the login and password for host from the netrc file, falling back to its
+// default entry. Returns nil if there's no netrc file or it has no entry for host.
+func netrcCredential(host string) (*Credential, error) {
+	data, err := os.ReadFile(netrcPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var found, fallback *Credential
+	var current *Credential
+
+	fields := netrcFields(string(data))
+	for i := 0; i < len(fields); i++ {
+		next := func() string {
+			if i+1 < len(fields) {
+				i++
+				return fields[i]
+			}
+			return ""
+		}
+
+		switch fields[i] {
+		case "machine":
+			current = &Credential{Host: next()}
+			if found == nil && strings.EqualFold(current.Host, host) {
+				found = current
+			}
+		case "default":
+			current = &Credential{Host: host}
+			if fallback == nil {
+				fallback = current
+			}
+		case "login":
+			if current != nil {
+				current.Username = next()
+			}
+		case "password":
+			if current != nil {
+				current.Password = next()
+			}
+		case "account":
+			next()
+		}
+	}
+
+	return pick(found, fallback), nil
+}
+
+// netrcFields splits a netrc file into its tokens, leaving out macro definitions, which run until
+// the next empty line
+func netrcFields(data string) []string {
+	var fields []string
+	inMacro := false
+
+	for _, line := range strings.Split(data, "\n") {
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		for _, field := range strings.Fields(line) {
+			if field == "macdef" {
+				inMacro = true
+				break
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+func pick(found *Credential, fallback *Credential) *Credential {
+	if found != nil {
+		return found
+	}
+
+	return fallback
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useNetrc points $NETRC at a file with contents for the rest of the test
+func useNetrc(t *testing.T, contents string) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "netrc")
+	if err := os.WriteFile(file, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	original, set := os.LookupEnv("NETRC")
+	os.Setenv("NETRC", file)
+	t.Cleanup(func() {
+		if set {
+			os.Setenv("NETRC", original)
+		} else {
+			os.Unsetenv("NETRC")
+		}
+	})
+}
+
+func TestNetrcCredential(t *testing.T) {
+	useNetrc(t, `machine github.com
+  login octocat
+  password s3cret
+
+macdef init
+machine macro.example.com login macro password macro
+
+machine example.com login user account acct password pass
+machine example.com login second password second
+default login anonymous password guest
+`)
+
+	tests := []struct {
+		host     string
+		username string
+		password string
+	}{
+		{"github.com", "octocat", "s3cret"},
+		{"GitHub.com", "octocat", "s3cret"},
+		{"example.com", "user", "pass"},
+		{"macro.example.com", "anonymous", "guest"},
+		{"unknown.com", "anonymous", "guest"},
+	}
+
+	for _, test := range tests {
+		cred, err := netrcCredential(test.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cred == nil || cred.Username != test.username || cred.Password != test.password {
+			t.Errorf("%s: expected %s:%s, got %+v", test.host, test.username, test.password, cred)
+		}
+	}
+}
+
+func TestNetrcCredentialWithoutEntry(t *testing.T) {
+	useNetrc(t, "machine github.com login octocat password s3cret\n")
+	if cred, err := netrcCredential("example.com"); cred != nil || err != nil {
+		t.Errorf("expected no credentials without a default entry, got %+v, %v", cred, err)
+	}
+
+	useNetrc(t, "")
+	os.Setenv("NETRC", filepath.Join(t.TempDir(), "missing"))
+	if cred, err := netrcCredential("github.com"); cred != nil || err != nil {
+		t.Errorf("expected no credentials without a netrc file, got %+v, %v", cred, err)
+	}
+}
@@ -0,0 +1,82 @@
+package hvm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/cache"
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/download"
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheList prints every download in the download cache, most recently used first
+func CacheList(ctx *context.Context, asJSON bool) error {
+	entries, err := cache.List()
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "URL\tSIZE\tDIGEST\tLAST USED")
+	for _, entry := range entries {
+		digest := entry.Digest
+		if i := strings.Index(digest, ":"); i >= 0 && len(digest) > i+13 {
+			digest = digest[:i+13]
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.URL, download.FormatBytes(entry.Size), digest,
+			entry.Used.Local().Format("2006-01-02 15:04"))
+	}
+
+	return nil
+}
+
+// CacheClean removes every download from the download cache
+func CacheClean(ctx *context.Context) error {
+	freed, err := cache.Clean()
+	if err != nil {
+		return err
+	}
+
+	colour.Printf("Removed all cached downloads, freed ^2%s^R\n", download.FormatBytes(freed))
+	return nil
+}
+
+// CachePrune removes downloads that haven't been used for longer than olderThan, e.g. "30d"
+func CachePrune(ctx *context.Context, olderThan string, dryRun bool) error {
+	maxAge, err := cache.ParseAge(olderThan)
+	if err != nil {
+		return err
+	}
+
+	removed, freed, err := cache.Prune(maxAge, dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+
+	for _, entry := range removed {
+		log.Infof(colour.Sprintf("%s cached download of ^2%s^R", verb, entry.URL))
+	}
+
+	colour.Printf("%s %d cached download(s), freeing ^2%s^R\n", verb, len(removed),
+		download.FormatBytes(freed))
+	return nil
+}
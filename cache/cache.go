@@ -0,0 +1,350 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josephschmitt/hvm/checksum"
+	"github.com/josephschmitt/hvm/paths"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	blobsDirectory = "blobs"
+	indexDirectory = "index"
+	tmpDirectory   = "tmp"
+)
+
+// Entry records a download in the cache: where it came from, and the blob holding its content
+type Entry struct {
+	URL     string    `json:"url"`
+	Digest  string    `json:"digest"`
+	File    string    `json:"file"`
+	Size    int64     `json:"size"`
+	Fetched time.Time `json:"fetched"`
+	Used    time.Time `json:"used"`
+}
+
+// Path returns the absolute path to the cached file
+func (entry *Entry) Path() string {
+	return filepath.Join(paths.AppPaths.CacheDirectory, entry.File)
+}
+
+// PartialFile returns the path to download url into. It lives in the cache directory so Store can
+// move it into place without copying. The path is the same every time, so a download that was
+// interrupted can be resumed.
+func PartialFile(url string) (string, error) {
+	dir := filepath.Join(paths.AppPaths.CacheDirectory, tmpDirectory)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	key := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, hex.EncodeToString(key[:8])+"-"+basename(url)), nil
+}
+
+// Lookup finds a cached download of url. If the download has a known digest, a blob with the same
+// content downloaded from any URL is used. The caller is expected to verify the content.
+func Lookup(url string, sum *checksum.Checksum) (*Entry, bool) {
+	if sum != nil && sum.Algorithm == checksum.SHA256 {
+		if entry, ok := lookupBlob(url, sum); ok {
+			return entry, true
+		}
+	}
+
+	entry, err := readEntry(indexPath(url))
+	if err != nil {
+		return nil, false
+	}
+
+	if _, err := os.Stat(entry.Path()); err != nil {
+		return nil, false
+	}
+
+	entry.touch()
+	return entry, true
+}
+
+func lookupBlob(url string, sum *checksum.Checksum) (*Entry, bool) {
+	dir := filepath.Join(paths.AppPaths.CacheDirectory, blobsDirectory, sum.Algorithm, sum.Digest)
+
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) == 0 {
+		return nil, false
+	}
+
+	info, err := files[0].Info()
+	if err != nil {
+		return nil, false
+	}
+
+	entry := &Entry{
+		URL:     url,
+		Digest:  sum.String(),
+		File:    filepath.Join(blobsDirectory, sum.Algorithm, sum.Digest, files[0].Name()),
+		Size:    info.Size(),
+		Fetched: info.ModTime(),
+	}
+
+	// Index the blob under this URL too, so it's found again without a checksum
+	if existing, err := readEntry(indexPath(url)); err == nil && existing.File == entry.File {
+		entry = existing
+	}
+
+	entry.touch()
+	return entry, true
+}
+
+// Store moves a completed download into the cache as the content of url, returning its entry. The
+// download is named after the sha256 digest of its content.
+func Store(url string, file string, digest *checksum.Checksum) (*Entry, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{
+		URL:     url,
+		Digest:  digest.String(),
+		File:    filepath.Join(blobsDirectory, digest.Algorithm, digest.Digest, basename(url)),
+		Size:    info.Size(),
+		Fetched: time.Now(),
+		Used:    time.Now(),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entry.Path()), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(file, entry.Path()); err != nil {
+		return nil, err
+	}
+
+	return entry, entry.save()
+}
+
+// Evict removes a download from the cache, e.g. because its content no longer verifies
+func Evict(entry *Entry) error {
+	if err := os.Remove(indexPath(entry.URL)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Dir(entry.Path())); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// List returns every download in the cache, most recently used first
+func List() ([]*Entry, error) {
+	files, err := filepath.Glob(filepath.Join(paths.AppPaths.CacheDirectory, indexDirectory,
+		"*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*Entry{}
+	for _, file := range files {
+		entry, err := readEntry(file)
+		if err != nil {
+			log.Debugf("Ignoring unreadable cache entry %s: %s", file, err)
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Used.After(entries[j].Used)
+	})
+
+	return entries, nil
+}
+
+// Prune removes downloads that haven't been used within maxAge, along with leftovers of
+// interrupted downloads. Returns the removed entries and how many bytes were freed.
+func Prune(maxAge time.Duration, dryRun bool) ([]*Entry, int64, error) {
+	entries, err := List()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var removed []*Entry
+	var freed int64
+	kept := make(map[string]bool)
+
+	for _, entry := range entries {
+		if time.Since(entry.Used) < maxAge {
+			kept[entry.File] = true
+			continue
+		}
+
+		removed = append(removed, entry)
+		if !dryRun {
+			if err := os.Remove(indexPath(entry.URL)); err != nil && !os.IsNotExist(err) {
+				return nil, 0, err
+			}
+		}
+	}
+
+	// Blobs are shared by every URL they were downloaded from, so only remove those no kept entry
+	// refers to
+	blobs, err := filepath.Glob(filepath.Join(paths.AppPaths.CacheDirectory, blobsDirectory, "*",
+		"*", "*"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, blob := range blobs {
+		rel, err := filepath.Rel(paths.AppPaths.CacheDirectory, blob)
+		if err != nil || kept[rel] {
+			continue
+		}
+
+		if info, err := os.Stat(blob); err == nil {
+			freed += info.Size()
+		}
+
+		if !dryRun {
+			if err := os.RemoveAll(filepath.Dir(blob)); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	tmpFiles, err := filepath.Glob(filepath.Join(paths.AppPaths.CacheDirectory, tmpDirectory, "*"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Anything this old isn't a download still in progress
+	for _, tmpFile := range tmpFiles {
+		info, err := os.Stat(tmpFile)
+		if err != nil || time.Since(info.ModTime()) < 24*time.Hour {
+			continue
+		}
+
+		freed += info.Size()
+		if !dryRun {
+			os.Remove(tmpFile)
+		}
+	}
+
+	return removed, freed, nil
+}
+
+// Clean removes every download from the cache, returning how many bytes were freed
+func Clean() (int64, error) {
+	freed, err := Size()
+	if err != nil {
+		return 0, err
+	}
+
+	return freed, os.RemoveAll(paths.AppPaths.CacheDirectory)
+}
+
+// Size returns the total size of the cache on disk
+func Size() (int64, error) {
+	var size int64
+	walk := func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	}
+
+	err := filepath.Walk(paths.AppPaths.CacheDirectory, walk)
+	return size, err
+}
+
+// ParseAge parses a duration like time.ParseDuration, with the addition of days ("30d") and weeks
+// ("2w")
+func ParseAge(age string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+
+	for suffix, unit := range units {
+		if n := strings.TrimSuffix(age, suffix); n != age {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration \"%s\"", age)
+			}
+
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+
+	return time.ParseDuration(age)
+}
+
+// basename returns the file name at the end of url, which is used to detect the archive format
+func basename(url string) string {
+	name := filepath.Base(url)
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" || name == "." || name == "/" {
+		name = "download"
+	}
+
+	return name
+}
+
+func indexPath(url string) string {
+	key := sha256.Sum256([]byte(url))
+	return filepath.Join(paths.AppPaths.CacheDirectory, indexDirectory,
+		hex.EncodeToString(key[:])+".json")
+}
+
+func readEntry(file string) (*Entry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (entry *Entry) save() error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(indexPath(entry.URL)), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(indexPath(entry.URL), data, 0644)
+}
+
+// touch records that the entry was just used, so prune keeps it around
+func (entry *Entry) touch() {
+	entry.Used = time.Now()
+	if err := entry.save(); err != nil {
+		log.Debugf("Unable to update cache entry for %s: %s", entry.URL, err)
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/josephschmitt/hvm/paths"
+)
+
+// usePaths points the cache at a temporary home directory for the rest of the test
+func usePaths(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	original := paths.AppPaths
+	paths.AppPaths = paths.NewPathsFromHome(dir, filepath.Join(dir, "home"),
+		filepath.Join(dir, "tmp"))
+	t.Cleanup(func() { paths.AppPaths = original })
+}
+
+func TestPartialFile(t *testing.T) {
+	usePaths(t)
+
+	urls := []string{
+		"https://example.com/v1/tool.tar.gz",
+		"https://example.com/v2/tool.tar.gz",
+		"https://mirror.example.com/v1/tool.tar.gz",
+		"https://example.com/v1/tool.tar.gz?arch=arm64",
+	}
+
+	seen := map[string]string{}
+	for _, url := range urls {
+		file, err := PartialFile(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if other, ok := seen[file]; ok {
+			t.Errorf("%s and %s share the partial download %s", url, other, file)
+		}
+		seen[file] = url
+
+		if !strings.HasSuffix(file, "-tool.tar.gz") {
+			t.Errorf("partial download %s doesn't keep the file name of %s", file, url)
+		}
+
+		// The same URL resumes the same file
+		again, err := PartialFile(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != file {
+			t.Errorf("expected %s to resume %s, got %s", url, file, again)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/alecthomas/colour"
+)
+
+const (
+	SHA256 = "sha256"
+	SHA512 = "sha512"
+)
+
+// Checksum is an expected digest for a downloaded file, along with the algorithm used to produce it
+type Checksum struct {
+	Algorithm string
+	Digest    string
+}
+
+func NewChecksum(algorithm string, digest string) (*Checksum, error) {
+	sum := &Checksum{
+		Algorithm: strings.ToLower(algorithm),
+		Digest:    strings.ToLower(strings.TrimSpace(digest)),
+	}
+
+	h, err := sum.NewHash()
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, err := hex.DecodeString(sum.Digest); err != nil || len(raw) != h.Size() {
+		return nil, fmt.Errorf(colour.Sprintf("invalid %s digest \"^1%s^R\"", sum.Algorithm,
+			sum.Digest))
+	}
+
+	return sum, nil
+}
+
+// Parse reads a checksum in the "algorithm:digest" form produced by String
+func Parse(value string) (*Checksum, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid checksum \"%s\", expected <algorithm>:<digest>", value)
+	}
+
+	return NewChecksum(parts[0], parts[1])
+}
+
+// FromHash creates a checksum from the digest computed by h
+func FromHash(algorithm string, h hash.Hash) *Checksum {
+	return &Checksum{
+		Algorithm: algorithm,
+		Digest:    hex.EncodeToString(h.Sum(nil)),
+	}
+}
+
+// NewHash returns a fresh hash.Hash for the checksum's algorithm, meant to be fed the download as
+// it streams in
+func (sum *Checksum) NewHash() (hash.Hash, error) {
+	switch sum.Algorithm {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported checksum algorithm \"%s\"", sum.Algorithm)
+}
+
+// Verify compares the digest computed by h against the expected digest
+func (sum *Checksum) Verify(h hash.Hash) error {
+	actual := hex.EncodeToString(h.Sum(nil))
+	if actual != sum.Digest {
+		return fmt.Errorf(colour.Sprintf("%s mismatch, expected ^2%s^R but got ^1%s^R",
+			sum.Algorithm, sum.Digest, actual))
+	}
+
+	return nil
+}
+
+func (sum *Checksum) String() string {
+	return fmt.Sprintf("%s:%s", sum.Algorithm, sum.Digest)
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type CacheCmd struct {
+	List  ListCmd  `kong:"cmd,aliases='ls',help='List cached downloads'"`
+	Clean CleanCmd `kong:"cmd,help='Remove every cached download'"`
+	Prune PruneCmd `kong:"cmd,help='Remove cached downloads that have not been used recently'"`
+}
+
+type ListCmd struct {
+	JSON bool `kong:"name='json',help='Print the list as JSON.'"`
+}
+
+func (c *ListCmd) Run(ctx *context.Context) error {
+	return hvm.CacheList(ctx, c.JSON)
+}
+
+type CleanCmd struct{}
+
+func (c *CleanCmd) Run(ctx *context.Context) error {
+	return hvm.CacheClean(ctx)
+}
+
+type PruneCmd struct {
+	OlderThan string `kong:"default='30d',help='Remove downloads last used longer ago than this, e.g. 30d, 2w or 12h.'"`
+	DryRun    bool   `kong:"help='Show what would be removed without removing anything.'"`
+}
+
+func (c *PruneCmd) Run(ctx *context.Context) error {
+	return hvm.CachePrune(ctx, c.OlderThan, c.DryRun)
+}
@@ -0,0 +1,14 @@
+package gc
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type GCCmd struct {
+	DryRun bool `kong:"help='Show what would be removed without removing anything.'"`
+}
+
+func (c *GCCmd) Run(ctx *context.Context) error {
+	return hvm.GC(ctx, c.DryRun)
+}
@@ -0,0 +1,16 @@
+package info
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type InfoCmd struct {
+	Name string `kong:"arg,help='Name of the package.'"`
+	Use  string `kong:"help='Version of the package to show.'"`
+	JSON bool   `kong:"name='json',help='Print the info as JSON.'"`
+}
+
+func (c *InfoCmd) Run(ctx *context.Context) error {
+	return hvm.Info(ctx, c.Name, c.Use, c.JSON)
+}
@@ -0,0 +1,14 @@
+package install
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type InstallCmd struct {
+	Name []string `kong:"arg,optional,help='Package(s) to install, as name or name@version. Defaults to every package in config.hcl.'"`
+}
+
+func (c *InstallCmd) Run(ctx *context.Context) error {
+	return hvm.Install(ctx, c.Name)
+}
@@ -0,0 +1,16 @@
+package lint
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type LintCmd struct {
+	Files        []string `kong:"arg,optional,type='path',help='Manifests, or directories of manifests, to lint. Defaults to the current directory.'"`
+	CheckSources bool     `kong:"help='Check that the source URL rendered for every platform exists.'"`
+	JSON         bool     `kong:"name='json',help='Print the problems as JSON.'"`
+}
+
+func (c *LintCmd) Run(ctx *context.Context) error {
+	return hvm.Lint(ctx, c.Files, c.CheckSources, c.JSON)
+}
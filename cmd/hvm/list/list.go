@@ -0,0 +1,15 @@
+package list
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type ListCmd struct {
+	Mode string `kong:"arg,optional,default='installed',enum='installed,linked,available',help='What to list: installed, linked or available packages.'"`
+	JSON bool   `kong:"name='json',help='Print the list as JSON.'"`
+}
+
+func (c *ListCmd) Run(ctx *context.Context) error {
+	return hvm.List(ctx, c.Mode, c.JSON)
+}
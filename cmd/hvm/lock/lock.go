@@ -0,0 +1,12 @@
+package lock
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type LockCmd struct{}
+
+func (c *LockCmd) Run(ctx *context.Context) error {
+	return hvm.Lock(ctx)
+}
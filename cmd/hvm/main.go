@@ -4,10 +4,20 @@ import (
 	_ "embed"
 	"os"
 
+	"github.com/josephschmitt/hvm/cmd/hvm/cache"
+	"github.com/josephschmitt/hvm/cmd/hvm/gc"
+	"github.com/josephschmitt/hvm/cmd/hvm/info"
+	"github.com/josephschmitt/hvm/cmd/hvm/install"
 	"github.com/josephschmitt/hvm/cmd/hvm/link"
+	"github.com/josephschmitt/hvm/cmd/hvm/lint"
+	"github.com/josephschmitt/hvm/cmd/hvm/list"
+	"github.com/josephschmitt/hvm/cmd/hvm/lock"
 	"github.com/josephschmitt/hvm/cmd/hvm/repos"
 	"github.com/josephschmitt/hvm/cmd/hvm/run"
+	"github.com/josephschmitt/hvm/cmd/hvm/search"
+	"github.com/josephschmitt/hvm/cmd/hvm/uninstall"
 	"github.com/josephschmitt/hvm/cmd/hvm/unlink"
+	"github.com/josephschmitt/hvm/cmd/hvm/verify"
 	"github.com/josephschmitt/hvm/cmd/hvm/version"
 	"github.com/josephschmitt/hvm/context"
 
@@ -17,16 +27,28 @@ import (
 )
 
 var hvm struct {
-	Debug string `kong:"default='warn',env='HVM_DEBUG'"`
+	Debug   string `kong:"default='warn',env='HVM_DEBUG'"`
+	Offline bool   `kong:"env='HVM_OFFLINE',help='Don\\'t use the network: only use already fetched repositories and cached downloads.'"`
 
 	Version            version.VersionFlag          `kong:"help='Show version information.'"`
 	VersionCmd         version.VersionCmd           `kong:"cmd,name='version',help='Show version information.'"`
 	InstallCompletions kongplete.InstallCompletions `kong:"cmd,help='Install shell completions'"`
 
-	Link        link.LinkCmd         `kong:"cmd,help='Link a new hermetic dependency library'"`
-	UnLink      unlink.UnLinkCmd     `kong:"cmd,aliases='unlink',help='Unlink an existing hermetic dependency library'"`
-	Run         run.RunCmd           `kong:"cmd,help='Run a hermetic dependency'"`
-	UpdateRepos repos.UpdateReposCmd `kong:"cmd,help='Updates the list of packages from the packages repositories'"`
+	Link        link.LinkCmd           `kong:"cmd,help='Link a new hermetic dependency library'"`
+	UnLink      unlink.UnLinkCmd       `kong:"cmd,aliases='unlink',help='Unlink an existing hermetic dependency library'"`
+	Run         run.RunCmd             `kong:"cmd,help='Run a hermetic dependency'"`
+	Install     install.InstallCmd     `kong:"cmd,help='Download and install hermetic dependencies without running them'"`
+	Uninstall   uninstall.UninstallCmd `kong:"cmd,help='Remove installed package versions'"`
+	List        list.ListCmd           `kong:"cmd,help='List installed, linked or available packages'"`
+	Search      search.SearchCmd       `kong:"cmd,help='Search package names and descriptions across all package repositories'"`
+	Info        info.InfoCmd           `kong:"cmd,help='Show the manifest of a package rendered for this platform'"`
+	Lint        lint.LintCmd           `kong:"cmd,help='Check package manifests for mistakes'"`
+	Lock        lock.LockCmd           `kong:"cmd,help='Write the resolved version, source and checksum of every package to hvm.lock'"`
+	Cache       cache.CacheCmd         `kong:"cmd,help='Manage the cache of downloaded package sources'"`
+	GC          gc.GCCmd               `kong:"cmd,name='gc',help='Remove installed package versions no longer referenced by any project'"`
+	Repos       repos.ReposCmd         `kong:"cmd,help='Manage package repositories'"`
+	UpdateRepos repos.UpdateReposCmd   `kong:"cmd,help='Updates the list of packages from the packages repositories'"`
+	Verify      verify.VerifyCmd       `kong:"cmd,help='Verify an installed package by running its manifest test'"`
 }
 
 func main() {
@@ -43,6 +65,7 @@ func main() {
 
 	ctx, err := context.NewContext(hvm.Debug)
 	kCtx.FatalIfErrorf(err)
+	ctx.SetOffline(hvm.Offline)
 
 	err = kCtx.Run(ctx)
 	kCtx.FatalIfErrorf(err)
@@ -3,6 +3,7 @@ package repos
 import (
 	"github.com/josephschmitt/hvm"
 	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/repos"
 )
 
 type UpdateReposCmd struct{}
@@ -10,3 +11,52 @@ type UpdateReposCmd struct{}
 func (c *UpdateReposCmd) Run(ctx *context.Context) error {
 	return hvm.UpdatePackagesRepos(ctx)
 }
+
+type ReposCmd struct {
+	Add    AddCmd    `kong:"cmd,help='Add a package repository to your user config'"`
+	Remove RemoveCmd `kong:"cmd,aliases='rm',help='Remove a package repository from your user config'"`
+	List   ListCmd   `kong:"cmd,aliases='ls',help='List package repositories in the order they are searched'"`
+	Update UpdateCmd `kong:"cmd,help='Update package repositories'"`
+}
+
+type AddCmd struct {
+	Name     string `kong:"arg,help='Name of the repository.'"`
+	URL      string `kong:"arg,name='url',help='Git URL, HTTP index or tarball URL, or local directory of the repository.'"`
+	Ref      string `kong:"help='Git tag, branch or commit to pin the repository to.'"`
+	Type     string `kong:"default='git',enum='git,http,local',help='Type of repository: git, http or local.'"`
+	Priority int    `kong:"help='Repositories with a higher priority are searched first.'"`
+}
+
+func (c *AddCmd) Run(ctx *context.Context) error {
+	return hvm.AddRepository(ctx, repos.Repository{
+		Name:     c.Name,
+		Type:     c.Type,
+		URL:      c.URL,
+		Ref:      c.Ref,
+		Priority: c.Priority,
+	})
+}
+
+type RemoveCmd struct {
+	Name string `kong:"arg,help='Name of the repository.'"`
+}
+
+func (c *RemoveCmd) Run(ctx *context.Context) error {
+	return hvm.RemoveRepository(ctx, c.Name)
+}
+
+type ListCmd struct {
+	JSON bool `kong:"name='json',help='Print the list as JSON.'"`
+}
+
+func (c *ListCmd) Run(ctx *context.Context) error {
+	return hvm.ListRepositories(ctx, c.JSON)
+}
+
+type UpdateCmd struct {
+	Name []string `kong:"arg,optional,help='Names of the repositories to update. Updates all of them if omitted.'"`
+}
+
+func (c *UpdateCmd) Run(ctx *context.Context) error {
+	return hvm.UpdateRepositories(ctx, c.Name)
+}
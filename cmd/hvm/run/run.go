@@ -3,6 +3,7 @@ package run
 import (
 	"github.com/josephschmitt/hvm"
 	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/repos"
 )
 
 type RunCmd struct {
@@ -16,7 +17,7 @@ type RunCmd struct {
 func (c *RunCmd) Run(ctx *context.Context) error {
 	bin := c.Bin
 	if bin == "" {
-		bin = c.Name
+		bin = repos.PackageName(c.Name)
 	}
 
 	if c.Use != "" {
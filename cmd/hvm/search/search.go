@@ -0,0 +1,15 @@
+package search
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type SearchCmd struct {
+	Term string `kong:"arg,help='Text to look for in package names and descriptions.'"`
+	JSON bool   `kong:"name='json',help='Print the results as JSON.'"`
+}
+
+func (c *SearchCmd) Run(ctx *context.Context) error {
+	return hvm.Search(ctx, c.Term, c.JSON)
+}
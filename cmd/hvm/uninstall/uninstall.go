@@ -0,0 +1,15 @@
+package uninstall
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type UninstallCmd struct {
+	Name []string `kong:"arg,help='Package version(s) to uninstall, as name@version.'"`
+	All  bool     `kong:"help='Uninstall every installed version of the named package(s).'"`
+}
+
+func (c *UninstallCmd) Run(ctx *context.Context) error {
+	return hvm.Uninstall(ctx, c.Name, c.All)
+}
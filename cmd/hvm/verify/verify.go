@@ -0,0 +1,19 @@
+package verify
+
+import (
+	"github.com/josephschmitt/hvm"
+	"github.com/josephschmitt/hvm/context"
+)
+
+type VerifyCmd struct {
+	Name string `kong:"arg,help='Package to verify.'"`
+	Use  string `kong:"help='Version of the package to verify, instead of the one set in config.hcl'"`
+}
+
+func (c *VerifyCmd) Run(ctx *context.Context) error {
+	if c.Use != "" {
+		ctx.UseVersion(c.Name, c.Use)
+	}
+
+	return hvm.Verify(ctx, c.Name)
+}
@@ -1,16 +1,26 @@
 package context
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
+	"github.com/josephschmitt/hvm/auth"
+	"github.com/josephschmitt/hvm/download"
+	"github.com/josephschmitt/hvm/lockfile"
 	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/mirror"
+	"github.com/josephschmitt/hvm/offline"
 	"github.com/kardianos/osext"
 
+	"github.com/alecthomas/colour"
 	"github.com/alecthomas/hcl"
 	"github.com/imdario/mergo"
 
 	"github.com/josephschmitt/hvm/paths"
+	"github.com/josephschmitt/hvm/repos"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -21,8 +31,33 @@ type Context struct {
 	Use     map[string]string
 	LinkDir string
 
-	Repositories []string
+	// Offline keeps hvm off the network, see SetOffline
+	Offline bool
+
+	// RepoUpdateInterval is how long package repositories go without being updated by hvm link
+	RepoUpdateInterval time.Duration
+
+	// DownloadAttempts is how many times a download is tried, and DownloadTimeout how long
+	// connecting or waiting on data may take before it's retried
+	DownloadAttempts int
+	DownloadTimeout  time.Duration
+
+	Repositories []*repos.Repository
 	Packages     map[string]*manifest.PackageManifestOptions
+
+	// PackageSources lists the config files with a package block for each package, and UseSource
+	// is the config file the use map came from
+	PackageSources map[string][]string
+	UseSource      string
+
+	// Mirrors rewrite package source URLs before they're downloaded, the first that applies wins
+	Mirrors []*mirror.Mirror
+
+	// Credentials authenticate downloads from private hosts, by host
+	Credentials []*auth.Credential
+
+	// Lock is the project's hvm.lock, or nil if it doesn't have one
+	Lock *lockfile.Lockfile
 }
 
 func NewContext(logLevel string) (*Context, error) {
@@ -50,25 +85,59 @@ func (ctx *Context) SetLogLevel(level string) (log.Level, error) {
 	return logLevel, nil
 }
 
+// SetOffline turns offline mode on or off: repositories aren't updated, manifests are resolved from
+// the repositories already fetched, and packages are only installed from the download cache
+func (ctx *Context) SetOffline(on bool) {
+	ctx.Offline = on
+	offline.Enable(on)
+}
+
 func (ctx *Context) Synthesize() error {
 	if ctx.Packages == nil {
 		ctx.Packages = make(map[string]*manifest.PackageManifestOptions)
 	}
 
 	configFiles := paths.AppPaths.ConfigFiles()
+	var loadedFiles []string
 
 	for _, confPath := range configFiles {
 		hclFile, err := os.ReadFile(confPath)
 		if err != nil {
 			continue
 		}
+		loadedFiles = append(loadedFiles, confPath)
 
 		foundConfig := &Config{}
 		hcl.Unmarshal(hclFile, foundConfig)
+		foundConfig.resolveRepositoryPaths(confPath)
 		if err := ctx.Merge(foundConfig); err != nil {
 			return err
 		}
+		ctx.recordSources(foundConfig, confPath)
+	}
+
+	registerConfigs(loadedFiles)
+	repos.Configure(ctx.Repositories)
+	auth.Configure(ctx.Credentials)
+
+	lock, err := lockfile.Load(lockfile.Path())
+	if err != nil {
+		return err
 	}
+	ctx.Lock = lock
+
+	if ctx.RepoUpdateInterval == 0 {
+		ctx.RepoUpdateInterval = repos.DefaultUpdateInterval
+	}
+
+	if ctx.DownloadAttempts == 0 {
+		ctx.DownloadAttempts = download.DefaultAttempts
+	}
+
+	if ctx.DownloadTimeout == 0 {
+		ctx.DownloadTimeout = download.DefaultTimeout
+	}
+	repos.SetTimeout(ctx.DownloadTimeout)
 
 	if ctx.LinkDir == "" {
 		binPath, err := osext.Executable()
@@ -99,6 +168,43 @@ func (ctx *Context) Merge(config *Config) error {
 		ctx.Packages[pkgConf.Name] = pkg
 	}
 
+	// Repositories declared closest to the working directory win
+	for i := range config.Repositories {
+		repo := config.Repositories[i]
+		if err := repo.Validate(); err != nil {
+			return err
+		}
+
+		if !ctx.hasRepository(repo.Name) {
+			ctx.Repositories = append(ctx.Repositories, &repo)
+		}
+	}
+
+	// Mirrors declared closest to the working directory are tried first, and win by name
+	for i := range config.Mirrors {
+		m := config.Mirrors[i]
+		if ctx.hasMirror(m.Name) {
+			continue
+		}
+
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		ctx.Mirrors = append(ctx.Mirrors, &m)
+	}
+
+	// Credentials declared closest to the working directory win
+	for i := range config.Credentials {
+		cred := config.Credentials[i]
+		if err := cred.Validate(); err != nil {
+			return err
+		}
+
+		if !ctx.hasCredential(cred.Host) {
+			ctx.Credentials = append(ctx.Credentials, &cred)
+		}
+	}
+
 	// Merge non-package fields
 	if ctx.Debug == nil {
 		ctx.SetLogLevel(config.Debug)
@@ -112,9 +218,85 @@ func (ctx *Context) Merge(config *Config) error {
 		ctx.LinkDir = paths.AppPaths.ResolveDir(config.LinkDir)
 	}
 
+	if ctx.RepoUpdateInterval == 0 && config.RepoUpdateInterval != "" {
+		interval, err := time.ParseDuration(config.RepoUpdateInterval)
+		if err != nil {
+			return fmt.Errorf(colour.Sprintf("invalid repo-update-interval ^1%s^R: %s",
+				config.RepoUpdateInterval, err))
+		}
+
+		// An interval of 0 updates on every link. Store it as negative so it isn't mistaken for
+		// being unset and replaced by the default.
+		if interval == 0 {
+			interval = -1
+		}
+		ctx.RepoUpdateInterval = interval
+	}
+
+	if ctx.DownloadAttempts == 0 {
+		ctx.DownloadAttempts = config.DownloadAttempts
+	}
+
+	if ctx.DownloadTimeout == 0 && config.DownloadTimeout != "" {
+		timeout, err := time.ParseDuration(config.DownloadTimeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf(colour.Sprintf("invalid download-timeout ^1%s^R, expected a "+
+				"positive duration like \"30s\"", config.DownloadTimeout))
+		}
+		ctx.DownloadTimeout = timeout
+	}
+
 	return nil
 }
 
+// recordSources remembers which config file set the use map and package blocks merged from config
+func (ctx *Context) recordSources(config *Config, confPath string) {
+	if ctx.PackageSources == nil {
+		ctx.PackageSources = make(map[string][]string)
+	}
+
+	for _, pkgConf := range config.Packages {
+		sources := ctx.PackageSources[pkgConf.Name]
+		if len(sources) == 0 || sources[len(sources)-1] != confPath {
+			ctx.PackageSources[pkgConf.Name] = append(sources, confPath)
+		}
+	}
+
+	if len(config.Use) > 0 {
+		ctx.UseSource = confPath
+	}
+}
+
+func (ctx *Context) hasRepository(name string) bool {
+	for _, repo := range ctx.Repositories {
+		if repo.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ctx *Context) hasMirror(name string) bool {
+	for _, m := range ctx.Mirrors {
+		if m.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ctx *Context) hasCredential(host string) bool {
+	for _, cred := range ctx.Credentials {
+		if cred.Host == host {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (ctx *Context) UseVersion(name string, version string) {
 	if ctx.Use == nil {
 		ctx.Use = make(map[string]string)
@@ -122,12 +304,78 @@ func (ctx *Context) UseVersion(name string, version string) {
 	ctx.Use[name] = version
 }
 
+// ConfiguredPackages returns the sorted names of every package referenced by the merged config,
+// either through the use map or a package block
+func (ctx *Context) ConfiguredPackages() []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	for name := range ctx.Use {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for name := range ctx.Packages {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// LoadConfig reads and parses a single config.hcl file
+func LoadConfig(confPath string) (*Config, error) {
+	hclFile, err := os.ReadFile(confPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := hcl.Unmarshal(hclFile, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
 // Config is the result of unmarshalling a config.hcl file
 type Config struct {
 	Debug    string            `hcl:"debug,optional"`
 	Use      map[string]string `hcl:"use,optional"`
 	LinkDir  string            `hcl:"linkdir,optional"`
 	Packages []PackageBlock    `hcl:"package,block,optional"`
+
+	Repositories       []repos.Repository `hcl:"repository,block,optional"`
+	RepoUpdateInterval string             `hcl:"repo-update-interval,optional"`
+
+	DownloadAttempts int    `hcl:"download-attempts,optional"`
+	DownloadTimeout  string `hcl:"download-timeout,optional"`
+
+	Mirrors     []mirror.Mirror   `hcl:"mirror,block,optional"`
+	Credentials []auth.Credential `hcl:"credentials,block,optional"`
+}
+
+// resolveRepositoryPaths makes relative local repository paths relative to the project the config
+// file belongs to, rather than the working directory
+func (config *Config) resolveRepositoryPaths(confPath string) {
+	projectDir := filepath.Dir(filepath.Dir(confPath))
+
+	for i, repo := range config.Repositories {
+		if repo.Type != repos.LocalRepository {
+			continue
+		}
+
+		dir := paths.AppPaths.ResolveDir(repo.URL)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(projectDir, dir)
+		}
+		config.Repositories[i].URL = dir
+	}
 }
 
 type PackageBlock struct {
@@ -0,0 +1,39 @@
+package context
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/josephschmitt/hvm/manifest"
+)
+
+func TestConfiguredPackages(t *testing.T) {
+	tests := []struct {
+		name     string
+		use      map[string]string
+		packages []string
+		expected []string
+	}{
+		{"nothing configured", nil, nil, nil},
+		{"use map", map[string]string{"node": "18", "go": "1.20"}, nil, []string{"go", "node"}},
+		{"package blocks", nil, []string{"yarn", "deno"}, []string{"deno", "yarn"}},
+		{
+			"both, without duplicates",
+			map[string]string{"node": "18", "go": "1.20"},
+			[]string{"node", "yarn"},
+			[]string{"go", "node", "yarn"},
+		},
+	}
+
+	for _, test := range tests {
+		ctx := &Context{Use: test.use, Packages: map[string]*manifest.PackageManifestOptions{}}
+		for _, name := range test.packages {
+			ctx.Packages[name] = &manifest.PackageManifestOptions{}
+		}
+
+		names := ctx.ConfiguredPackages()
+		if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%s: got %q, expected %q", test.name, names, test.expected)
+		}
+	}
+}
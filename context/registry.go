@@ -0,0 +1,107 @@
+package context
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/josephschmitt/hvm/paths"
+	log "github.com/sirupsen/logrus"
+)
+
+const registryFile = "projects.json"
+
+// Registry tracks every config.hcl file hvm has loaded, so that commands like gc can tell which
+// package versions are still referenced by some project
+type Registry struct {
+	Configs []string `json:"configs"`
+}
+
+func registryPath() string {
+	return filepath.Join(paths.AppPaths.ConfigDirectory, registryFile)
+}
+
+func LoadRegistry() (*Registry, error) {
+	registry := &Registry{}
+
+	data, err := os.ReadFile(registryPath())
+	if os.IsNotExist(err) {
+		return registry, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, registry); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+func (r *Registry) Save() error {
+	sort.Strings(r.Configs)
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(registryPath()), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(registryPath(), data, 0644)
+}
+
+// Add records config paths in the registry, returning true if any of them weren't already in it
+func (r *Registry) Add(configPaths ...string) bool {
+	known := make(map[string]bool)
+	for _, confPath := range r.Configs {
+		known[confPath] = true
+	}
+
+	added := false
+	for _, confPath := range configPaths {
+		if !known[confPath] {
+			known[confPath] = true
+			r.Configs = append(r.Configs, confPath)
+			added = true
+		}
+	}
+
+	return added
+}
+
+// Prune drops configs that no longer exist on disk, returning the ones that are left
+func (r *Registry) Prune() []string {
+	var configs []string
+	for _, confPath := range r.Configs {
+		if _, err := os.Stat(confPath); err == nil {
+			configs = append(configs, confPath)
+		}
+	}
+
+	r.Configs = configs
+	return configs
+}
+
+// registerConfigs adds config paths to the registry on disk. Failures are only logged, since the
+// registry is bookkeeping that shouldn't get in the way of running packages.
+func registerConfigs(configPaths []string) {
+	if len(configPaths) == 0 {
+		return
+	}
+
+	registry, err := LoadRegistry()
+	if err != nil {
+		log.Debugf("Unable to load config registry: %s", err)
+		return
+	}
+
+	if registry.Add(configPaths...) {
+		if err := registry.Save(); err != nil {
+			log.Debugf("Unable to save config registry: %s", err)
+		}
+	}
+}
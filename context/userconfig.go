@@ -0,0 +1,142 @@
+package context
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/colour"
+	"github.com/alecthomas/hcl"
+	"github.com/josephschmitt/hvm/paths"
+	"github.com/josephschmitt/hvm/repos"
+)
+
+// UserConfigPath returns the path to the user-level config.hcl in the home directory
+func UserConfigPath() string {
+	return filepath.Join(paths.AppPaths.HomeDirectory, ".hvm", "config.hcl")
+}
+
+// AddRepository appends a repository block to the user-level config.hcl. The rest of the file is
+// left untouched.
+func AddRepository(repo repos.Repository) error {
+	confPath := UserConfigPath()
+
+	data, err := os.ReadFile(confPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	config := &Config{}
+	if err := hcl.Unmarshal(data, config); err != nil {
+		return fmt.Errorf(colour.Sprintf("unable to parse ^6%s^R: %s", confPath, err))
+	}
+
+	for _, existing := range config.Repositories {
+		if existing.Name == repo.Name {
+			return fmt.Errorf(colour.Sprintf("repository ^3%s^R already exists in ^6%s^R",
+				repo.Name, confPath))
+		}
+	}
+
+	block, err := hcl.Marshal(&Config{Repositories: []repos.Repository{repo}})
+	if err != nil {
+		return err
+	}
+
+	if len(data) > 0 {
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		data = append(data, '\n')
+	}
+	data = append(data, block...)
+
+	if err := os.MkdirAll(filepath.Dir(confPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(confPath, data, 0644)
+}
+
+// RemoveRepository removes a repository block from the user-level config.hcl. The rest of the file
+// is left untouched. Returns false if the file doesn't declare the repository.
+func RemoveRepository(name string) (bool, error) {
+	confPath := UserConfigPath()
+
+	data, err := os.ReadFile(confPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	ast, err := hcl.ParseBytes(data)
+	if err != nil {
+		return false, fmt.Errorf(colour.Sprintf("unable to parse ^6%s^R: %s", confPath, err))
+	}
+
+	for _, entry := range ast.Entries {
+		block := entry.Block
+		if block == nil || block.Name != "repository" || len(block.Labels) == 0 ||
+			block.Labels[0] != name {
+			continue
+		}
+
+		// Comments trailing the previous line are attributed to the block, but belong to that line
+		start := block.Pos.Offset
+		lineStart := bytes.LastIndexByte(data[:start], '\n') + 1
+		if len(bytes.TrimSpace(data[lineStart:start])) > 0 {
+			start += bytes.IndexByte(data[start:], '\n') + 1
+		}
+
+		end, err := blockEnd(data, start)
+		if err != nil {
+			return false, fmt.Errorf(colour.Sprintf("unable to parse ^6%s^R: %s", confPath, err))
+		}
+
+		// Take the blank line separating the block from the next one along with it
+		for _, suffix := range []string{"\n", "\n"} {
+			if bytes.HasPrefix(data[end:], []byte(suffix)) {
+				end += len(suffix)
+			}
+		}
+
+		data = append(data[:start:start], data[end:]...)
+		return true, os.WriteFile(confPath, data, 0644)
+	}
+
+	return false, nil
+}
+
+// blockEnd returns the offset just past the closing brace of the block starting at start, skipping
+// over braces inside strings and comments
+func blockEnd(data []byte, start int) (int, error) {
+	depth := 0
+	inString := false
+
+	for i := start; i < len(data); i++ {
+		c := data[i]
+
+		switch {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '#' || (c == '/' && i+1 < len(data) && data[i+1] == '/'):
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated block")
+}
@@ -0,0 +1,151 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/josephschmitt/hvm/paths"
+	"github.com/josephschmitt/hvm/repos"
+)
+
+// usePaths points the user config at a temporary home directory for the rest of the test
+func usePaths(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	original := paths.AppPaths
+	paths.AppPaths = paths.NewPathsFromHome(dir, filepath.Join(dir, "home"),
+		filepath.Join(dir, "tmp"))
+	t.Cleanup(func() { paths.AppPaths = original })
+}
+
+func writeUserConfig(t *testing.T, config string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(UserConfigPath()), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(UserConfigPath(), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readUserConfig(t *testing.T) string {
+	t.Helper()
+
+	data, err := os.ReadFile(UserConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestAddRepository(t *testing.T) {
+	usePaths(t)
+
+	repo := repos.Repository{Name: "internal", URL: "https://example.com/packages.git", Ref: "v1"}
+	if err := AddRepository(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(UserConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Repositories) != 1 || config.Repositories[0] != repo {
+		t.Errorf("expected %+v to be added, got %+v", repo, config.Repositories)
+	}
+
+	if err := AddRepository(repo); err == nil {
+		t.Error("expected adding a repository twice to fail")
+	}
+
+	// The rest of the file is left as it was
+	writeUserConfig(t, "# My config\nuse = {\n  \"node\": \"18\"\n}")
+	if err := AddRepository(repo); err != nil {
+		t.Fatal(err)
+	}
+	if actual := readUserConfig(t); !strings.HasPrefix(actual,
+		"# My config\nuse = {\n  \"node\": \"18\"\n}\n\nrepository \"internal\" {") {
+		t.Errorf("expected the repository to be appended after a blank line, got:\n%s", actual)
+	}
+
+	writeUserConfig(t, "use = {")
+	if err := AddRepository(repo); err == nil {
+		t.Error("expected an unparsable config to fail")
+	}
+}
+
+func TestRemoveRepository(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		remove   string
+		removed  bool
+		expected string
+	}{
+		{
+			"only repository",
+			"repository \"internal\" {\n  url = \"https://example.com\"\n}\n",
+			"internal",
+			true,
+			"",
+		},
+		{
+			"between others",
+			"repository \"a\" {\n  url = \"a\"\n}\n\nrepository \"b\" {\n  url = \"b\"\n}\n\n" +
+				"repository \"c\" {\n  url = \"c\"\n}\n",
+			"b",
+			true,
+			"repository \"a\" {\n  url = \"a\"\n}\n\nrepository \"c\" {\n  url = \"c\"\n}\n",
+		},
+		{
+			"with its comment",
+			"use = {\n  \"node\": \"18\"\n}\n\n# Team packages\nrepository \"internal\" {\n" +
+				"  url = \"https://example.com\"\n}\n",
+			"internal",
+			true,
+			"use = {\n  \"node\": \"18\"\n}\n\n",
+		},
+		{
+			"braces in strings and comments",
+			"repository \"internal\" {\n  # not a } brace\n  url = \"https://example.com/{}\"\n}\n" +
+				"repository \"kept\" {\n  url = \"kept\"\n}\n",
+			"internal",
+			true,
+			"repository \"kept\" {\n  url = \"kept\"\n}\n",
+		},
+		{
+			"not declared",
+			"repository \"internal\" {\n  url = \"https://example.com\"\n}\n",
+			"other",
+			false,
+			"repository \"internal\" {\n  url = \"https://example.com\"\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		usePaths(t)
+		writeUserConfig(t, test.config)
+
+		removed, err := RemoveRepository(test.remove)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if removed != test.removed {
+			t.Errorf("%s: expected removed=%t", test.name, test.removed)
+		}
+		if actual := readUserConfig(t); actual != test.expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.name, test.expected, actual)
+		}
+	}
+
+	usePaths(t)
+	if removed, err := RemoveRepository("internal"); removed || err != nil {
+		t.Errorf("expected nothing to remove without a user config, got %t, %v", removed, err)
+	}
+}
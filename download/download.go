@@ -0,0 +1,298 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultAttempts is how many times a download is tried before giving up
+	DefaultAttempts = 5
+	// DefaultTimeout is how long connecting, or waiting on data, may take before a download is
+	// retried
+	DefaultTimeout = 30 * time.Second
+
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+// Options control how a download is retried and reported
+type Options struct {
+	// Label names the download in progress output
+	Label string
+	// Attempts is how many times a download is tried, with exponential backoff in between
+	Attempts int
+	// Timeout bounds connecting to the server and each wait for data, not the whole download
+	Timeout time.Duration
+	// Hashes are fed the whole file as it's written, including any part resumed from an earlier
+	// download, so it can be verified without reading it again
+	Hashes []hash.Hash
+}
+
+// StatusError is returned when the server responds to a download with an error status
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (err *StatusError) Error() string {
+	return err.Status
+}
+
+// transientError is a failure that may not happen again, like a dropped connection
+type transientError struct {
+	err error
+}
+
+func (err *transientError) Error() string {
+	return err.err.Error()
+}
+
+func (err *transientError) Unwrap() error {
+	return err.err
+}
+
+// Fetch downloads url to dest. If dest already holds the start of the file, e.g. from a download
+// that was interrupted, the rest of it is requested with a Range header. Connection errors,
+// stalls and 5xx responses are retried with exponential backoff, resuming each time. On failure,
+// dest is left in place so a later Fetch can resume it.
+func Fetch(url string, dest string, opts *Options) error {
+	attempts := opts.Attempts
+	if attempts < 1 {
+		attempts = DefaultAttempts
+	}
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fetch(url, dest, opts)
+		if err == nil || !retryable(err) || attempt >= attempts {
+			return err
+		}
+
+		log.Warnf(colour.Sprintf("Downloading ^3%s^R failed, retrying in %s (attempt %d of "+
+			"%d): %s", opts.Label, backoff, attempt+1, attempts, err))
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var transient *transientError
+	return errors.As(err, &transient)
+}
+
+func fetch(url string, dest string, opts *Options) error {
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	if err := auth.Authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := NewClient(opts.Timeout).Do(req)
+	if err != nil {
+		return &transientError{err}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && rangeStart(resp) == offset:
+		log.Infof(colour.Sprintf("Resuming download of ^3%s^R at %s\n", opts.Label,
+			FormatBytes(offset)))
+	case resp.StatusCode == http.StatusPartialContent:
+		err := fmt.Errorf("server resumed the download at the wrong offset")
+		return restart(file, &transientError{err})
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		if rangeSize(resp) == offset {
+			return hashExisting(file, offset, opts.Hashes)
+		}
+
+		// What was downloaded before doesn't belong to this file anymore
+		err := fmt.Errorf("unable to resume download: %s", resp.Status)
+		return restart(file, &transientError{err})
+	case resp.StatusCode >= 400:
+		return &StatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	case offset > 0:
+		log.Debugf(colour.Sprintf("Server doesn't support resuming ^3%s^R, starting over\n",
+			opts.Label))
+		if err := restart(file, nil); err != nil {
+			return err
+		}
+		offset = 0
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	if err := hashExisting(file, offset, opts.Hashes); err != nil {
+		return err
+	}
+
+	progress := newProgress(opts.Label, offset, total)
+	defer progress.finish()
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	// Cancel the request if the server stops sending data, rather than waiting forever
+	stalled := time.AfterFunc(timeout, cancel)
+	defer stalled.Stop()
+
+	body := &stallReader{reader: resp.Body, timer: stalled, timeout: timeout}
+	writers := []io.Writer{file, progress}
+	for _, h := range opts.Hashes {
+		writers = append(writers, h)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), body); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("no data received for %s", timeout)
+		}
+		return &transientError{err}
+	}
+
+	return file.Close()
+}
+
+// restart truncates a partial download so it's downloaded from the beginning, returning err
+func restart(file *os.File, err error) error {
+	if truncErr := file.Truncate(0); truncErr != nil {
+		return truncErr
+	}
+
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		return seekErr
+	}
+
+	return err
+}
+
+// hashExisting resets hashes and feeds them the first offset bytes of file, the part of the
+// download that's being resumed, leaving file positioned at offset
+func hashExisting(file *os.File, offset int64, hashes []hash.Hash) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	writers := make([]io.Writer, len(hashes))
+	for i, h := range hashes {
+		h.Reset()
+		writers[i] = h
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := io.CopyN(io.MultiWriter(writers...), file, offset); err != nil {
+		return err
+	}
+
+	_, err := file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// NewClient returns an HTTP client that gives up on connecting, or waiting for a response, after
+// timeout. Reading the body isn't bounded, since downloads can legitimately take a long time.
+func NewClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: timeout}).DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+		},
+	}
+}
+
+// rangeStart returns the offset of the first byte in a partial response, as in
+// "Content-Range: bytes 100-199/200"
+func rangeStart(resp *http.Response) int64 {
+	contentRange := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if i := strings.Index(contentRange, "-"); i >= 0 {
+		if start, err := strconv.ParseInt(contentRange[:i], 10, 64); err == nil {
+			return start
+		}
+	}
+
+	return -1
+}
+
+// rangeSize returns the size of the whole file from a range response, as in
+// "Content-Range: bytes */200"
+func rangeSize(resp *http.Response) int64 {
+	contentRange := resp.Header.Get("Content-Range")
+	if i := strings.LastIndex(contentRange, "/"); i >= 0 {
+		if size, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+			return size
+		}
+	}
+
+	return -1
+}
+
+// stallReader pushes back a timer every time data is read
+type stallReader struct {
+	reader  io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+
+	return n, err
+}
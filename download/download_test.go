@@ -0,0 +1,257 @@
+package download
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var content = bytes.Repeat([]byte("0123456789abcdef"), 4096)
+
+// fileServer serves content, handling Range requests unless handler takes over the request. The
+// ranges requested are recorded in order.
+type fileServer struct {
+	*httptest.Server
+
+	requests int32
+	ranges   []string
+	handler  func(w http.ResponseWriter, r *http.Request, request int32) bool
+}
+
+func newFileServer(
+	t *testing.T,
+	handler func(w http.ResponseWriter, r *http.Request, request int32) bool,
+) *fileServer {
+	t.Helper()
+
+	server := &fileServer{handler: handler}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := atomic.AddInt32(&server.requests, 1)
+		server.ranges = append(server.ranges, r.Header.Get("Range"))
+
+		if server.handler != nil && server.handler(w, r, request) {
+			return
+		}
+
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// partial creates a download destination that already holds data
+func partial(t *testing.T, data []byte) string {
+	t.Helper()
+
+	dest := filepath.Join(t.TempDir(), "file.partial")
+	if data != nil {
+		if err := os.WriteFile(dest, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dest
+}
+
+func checkDownload(t *testing.T, name string, dest string, opts *Options) {
+	t.Helper()
+
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("%s: downloaded %d bytes that don't match the %d served", name, len(data),
+			len(content))
+	}
+
+	expected := sha256.Sum256(content)
+	if actual := opts.Hashes[0].Sum(nil); !bytes.Equal(actual, expected[:]) {
+		t.Errorf("%s: expected the hash of the whole file %x, got %x", name, expected, actual)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	half := len(content) / 2
+
+	tests := []struct {
+		name     string
+		existing []byte
+		handler  func(w http.ResponseWriter, r *http.Request, request int32) bool
+		ranges   string
+	}{
+		{"fresh", nil, nil, ""},
+		{"resumed", content[:half], nil, fmt.Sprintf("bytes=%d-", half)},
+		{"already complete", content, nil, fmt.Sprintf("bytes=%d-", len(content))},
+		{"resuming unsupported", content[:half], func(w http.ResponseWriter, r *http.Request,
+			_ int32) bool {
+			w.Write(content)
+			return true
+		}, fmt.Sprintf("bytes=%d-", half)},
+	}
+
+	for _, test := range tests {
+		server := newFileServer(t, test.handler)
+		dest := partial(t, test.existing)
+		opts := &Options{Label: test.name, Attempts: 1, Hashes: []hash.Hash{sha256.New()}}
+
+		if err := Fetch(server.URL, dest, opts); err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		checkDownload(t, test.name, dest, opts)
+		if actual := strings.Join(server.ranges, ","); actual != test.ranges {
+			t.Errorf("%s: expected ranges %q to be requested, got %q", test.name, test.ranges,
+				actual)
+		}
+	}
+}
+
+func TestFetchRestarts(t *testing.T) {
+	half := len(content) / 2
+
+	tests := []struct {
+		name     string
+		existing []byte
+		handler  func(w http.ResponseWriter, r *http.Request, request int32) bool
+		ranges   string
+	}{
+		{"partial file changed upstream", append(append([]byte{}, content...), "stale"...), nil,
+			fmt.Sprintf("bytes=%d-,", len(content)+5)},
+		{"resumed at the wrong offset", content[:half], func(w http.ResponseWriter,
+			r *http.Request, request int32) bool {
+			if request > 1 {
+				return false
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1,
+				len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content)
+			return true
+		}, fmt.Sprintf("bytes=%d-,", half)},
+		{"server error", nil, func(w http.ResponseWriter, r *http.Request, request int32) bool {
+			if request > 1 {
+				return false
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}, ","},
+	}
+
+	for _, test := range tests {
+		server := newFileServer(t, test.handler)
+		dest := partial(t, test.existing)
+		opts := &Options{Label: test.name, Attempts: 2, Hashes: []hash.Hash{sha256.New()}}
+
+		if err := Fetch(server.URL, dest, opts); err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		checkDownload(t, test.name, dest, opts)
+		if actual := strings.Join(server.ranges, ","); actual != test.ranges {
+			t.Errorf("%s: expected ranges %q to be requested, got %q", test.name, test.ranges,
+				actual)
+		}
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		requests int32
+	}{
+		{"not found", http.StatusNotFound, 1},
+		{"forbidden", http.StatusForbidden, 1},
+		{"too many requests", http.StatusTooManyRequests, 2},
+		{"server error", http.StatusInternalServerError, 2},
+	}
+
+	for _, test := range tests {
+		server := newFileServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) bool {
+			w.WriteHeader(test.status)
+			return true
+		})
+
+		err := Fetch(server.URL, partial(t, nil), &Options{Label: test.name, Attempts: 2})
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != test.status {
+			t.Errorf("%s: expected a %d status error, got %v", test.name, test.status, err)
+		}
+		if server.requests != test.requests {
+			t.Errorf("%s: expected %d request(s), got %d", test.name, test.requests,
+				server.requests)
+		}
+	}
+}
+
+func TestFetchStalled(t *testing.T) {
+	half := len(content) / 2
+	release := make(chan struct{})
+	defer close(release)
+
+	server := newFileServer(t, func(w http.ResponseWriter, r *http.Request, request int32) bool {
+		if request > 1 {
+			return false
+		}
+
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		w.Write(content[:half])
+		w.(http.Flusher).Flush()
+		<-release
+		return true
+	})
+
+	dest := partial(t, nil)
+	opts := &Options{Label: "stalled", Attempts: 1, Timeout: 100 * time.Millisecond,
+		Hashes: []hash.Hash{sha256.New()}}
+
+	if err := Fetch(server.URL, dest, opts); err == nil ||
+		!strings.Contains(err.Error(), "no data received") {
+		t.Fatalf("expected the stalled download to time out, got %v", err)
+	}
+
+	// What was received is kept to resume from
+	if info, err := os.Stat(dest); err != nil || info.Size() != int64(half) {
+		t.Fatalf("expected %d bytes to be kept, got %v, %v", half, info, err)
+	}
+
+	if err := Fetch(server.URL, dest, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, "stalled", dest, opts)
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+
+	for _, test := range tests {
+		if actual := FormatBytes(test.size); actual != test.expected {
+			t.Errorf("%d: expected %s, got %s", test.size, test.expected, actual)
+		}
+	}
+}
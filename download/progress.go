@@ -0,0 +1,128 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/colour"
+	"github.com/mattn/go-isatty"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// progressDelay keeps quick downloads from flashing a progress bar
+	progressDelay = 500 * time.Millisecond
+	// barInterval is how often the progress bar is redrawn on a terminal
+	barInterval = 100 * time.Millisecond
+	// logInterval is how often progress is logged when stderr isn't a terminal
+	logInterval = 10 * time.Second
+
+	barWidth = 30
+)
+
+// progress reports how far along a download is. On a terminal it draws a progress bar on stderr,
+// otherwise it logs a line every logInterval.
+type progress struct {
+	label   string
+	offset  int64
+	written int64
+	total   int64
+
+	started  time.Time
+	reported time.Time
+	terminal bool
+	drawn    bool
+}
+
+func newProgress(label string, offset int64, total int64) *progress {
+	return &progress{
+		label:    label,
+		offset:   offset,
+		written:  offset,
+		total:    total,
+		started:  time.Now(),
+		reported: time.Now(),
+		terminal: isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()),
+	}
+}
+
+func (p *progress) Write(data []byte) (int, error) {
+	p.written += int64(len(data))
+
+	now := time.Now()
+	if now.Sub(p.started) < progressDelay {
+		return len(data), nil
+	}
+
+	if p.terminal && now.Sub(p.reported) >= barInterval {
+		p.draw()
+		p.reported = now
+	} else if !p.terminal && now.Sub(p.reported) >= logInterval {
+		log.Infof(colour.Sprintf("Downloading ^3%s^R: %s\n", p.label, p.status()))
+		p.reported = now
+	}
+
+	return len(data), nil
+}
+
+// finish draws the final state of the progress bar, if it was drawn at all
+func (p *progress) finish() {
+	if p.drawn {
+		p.draw()
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *progress) draw() {
+	bar := ""
+	if p.total > 0 {
+		filled := int(float64(barWidth) * float64(p.written) / float64(p.total))
+		if filled > barWidth {
+			filled = barWidth
+		}
+		bar = fmt.Sprintf("[%s%s] ", strings.Repeat("=", filled),
+			strings.Repeat(" ", barWidth-filled))
+	}
+
+	fmt.Fprintf(os.Stderr, "\r\033[K%s %s%s", p.label, bar, p.status())
+	p.drawn = true
+}
+
+// status describes the bytes downloaded, the rate and how long is left, e.g.
+// "12.0 MiB / 48.0 MiB (25%), 2.0 MiB/s, ETA 18s"
+func (p *progress) status() string {
+	elapsed := time.Since(p.started).Seconds()
+	rate := float64(p.written-p.offset) / elapsed
+
+	if p.total <= 0 {
+		return fmt.Sprintf("%s, %s/s", FormatBytes(p.written), FormatBytes(int64(rate)))
+	}
+
+	status := fmt.Sprintf("%s / %s (%d%%), %s/s", FormatBytes(p.written), FormatBytes(p.total),
+		p.written*100/p.total, FormatBytes(int64(rate)))
+
+	if rate > 0 && p.written < p.total {
+		eta := time.Duration(float64(p.total-p.written)/rate) * time.Second
+		status += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+	}
+
+	return status
+}
+
+// FormatBytes formats a size in bytes using binary units, e.g. "1.5 MiB"
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,368 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/colour"
+	log "github.com/sirupsen/logrus"
+	"github.com/ulikunitz/xz"
+)
+
+type Format string
+
+const (
+	Unknown  Format = ""
+	Tar      Format = "tar"
+	TarGzip  Format = "tar.gz"
+	TarBzip2 Format = "tar.bz2"
+	TarXz    Format = "tar.xz"
+	Zip      Format = "zip"
+	Gzip     Format = "gz"
+)
+
+var extensions = []struct {
+	suffix string
+	format Format
+}{
+	{".tar.gz", TarGzip},
+	{".tgz", TarGzip},
+	{".tar.bz2", TarBzip2},
+	{".tbz2", TarBzip2},
+	{".tbz", TarBzip2},
+	{".tar.xz", TarXz},
+	{".txz", TarXz},
+	{".tar", Tar},
+	{".zip", Zip},
+	{".gz", Gzip},
+}
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zipMagic   = []byte("PK\x03\x04")
+	tarMagic   = []byte("ustar")
+)
+
+// tarMagicOffset is where the "ustar" magic lives inside a tar header block
+const tarMagicOffset = 257
+
+// Options controls which entries of an archive are written to disk, and where
+type Options struct {
+	// Number of leading path components to remove from each entry, like tar's --strip-components
+	StripComponents int
+	// Only extract entries within this directory of the archive (after stripping components),
+	// relative to the directory itself
+	Subdir string
+}
+
+// DetectFormat determines the archive format of the file at filePath by its extension. Files
+// without a known archive extension are only recognized by their magic bytes if sniff is set,
+// since plenty of files installed as-is are archives too (e.g. a .jar is a zip). Returns Unknown
+// for files that aren't a supported archive.
+func DetectFormat(filePath string, sniff bool) (Format, error) {
+	name := strings.ToLower(filepath.Base(filePath))
+	for _, ext := range extensions {
+		if strings.HasSuffix(name, ext.suffix) {
+			return ext.format, nil
+		}
+	}
+
+	if !sniff {
+		return Unknown, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Unknown, err
+	}
+	defer file.Close()
+
+	return sniffFormat(file)
+}
+
+func sniffFormat(file io.Reader) (Format, error) {
+	header := make([]byte, tarMagicOffset+len(tarMagic))
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return Unknown, err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return Zip, nil
+	case bytes.HasPrefix(header, xzMagic):
+		return TarXz, nil
+	case bytes.HasPrefix(header, bzip2Magic):
+		return TarBzip2, nil
+	case bytes.HasPrefix(header, gzipMagic):
+		// Peek inside the gzip stream to tell a tarball apart from a single compressed file
+		gz, err := gzip.NewReader(io.MultiReader(bytes.NewReader(header), file))
+		if err != nil {
+			return Unknown, err
+		}
+		defer gz.Close()
+
+		if format, err := sniffFormat(gz); err == nil && format == Tar {
+			return TarGzip, nil
+		}
+		return Gzip, nil
+	case isTar(header):
+		return Tar, nil
+	}
+
+	return Unknown, nil
+}
+
+func isTar(header []byte) bool {
+	return len(header) >= tarMagicOffset+len(tarMagic) &&
+		bytes.Equal(header[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic)
+}
+
+// Extract unpacks the archive at filePath into outDir. For single-file gzip archives, the
+// decompressed file is written to outDir/name.
+func Extract(filePath string, format Format, outDir string, name string, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	log.Debugf(colour.Sprintf("Extract ^6%s^R as ^5%s^R into ^3%s^R\n", filePath, format, outDir))
+
+	switch format {
+	case Tar:
+		return extractTar(file, outDir, opts)
+	case TarGzip:
+		gz, err := gzip.NewReader(bufio.NewReader(file))
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return extractTar(gz, outDir, opts)
+	case TarBzip2:
+		return extractTar(bzip2.NewReader(bufio.NewReader(file)), outDir, opts)
+	case TarXz:
+		xzr, err := xz.NewReader(bufio.NewReader(file))
+		if err != nil {
+			return err
+		}
+		return extractTar(xzr, outDir, opts)
+	case Zip:
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		return extractZip(file, info.Size(), outDir, opts)
+	case Gzip:
+		gz, err := gzip.NewReader(bufio.NewReader(file))
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return writeFile(filepath.Join(outDir, name), gz, 0755)
+	}
+
+	return fmt.Errorf("unsupported archive format \"%s\" for %s", format, filePath)
+}
+
+func extractTar(r io.Reader, outDir string, opts *Options) error {
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		target, ok, err := resolveEntry(outDir, header.Name, opts)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := writeFile(target, tr, os.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := writeSymlink(outDir, target, header.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			source, ok, err := resolveEntry(outDir, header.Linkname, opts)
+			if err != nil {
+				return err
+			} else if !ok {
+				log.Debugf("Skipping hard link %s to filtered entry %s", header.Name, header.Linkname)
+				continue
+			}
+
+			os.Remove(target)
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		default:
+			log.Debugf("Skipping unsupported tar entry %s (type %c)", header.Name, header.Typeflag)
+		}
+	}
+}
+
+func extractZip(r io.ReaderAt, size int64, outDir string, opts *Options) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range zr.File {
+		target, ok, err := resolveEntry(outDir, entry.Name, opts)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			rc, err := entry.Open()
+			if err != nil {
+				return err
+			}
+			link, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+
+			if err := writeSymlink(outDir, target, string(link)); err != nil {
+				return err
+			}
+		default:
+			rc, err := entry.Open()
+			if err != nil {
+				return err
+			}
+			err = writeFile(target, rc, mode.Perm())
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveEntry maps an archive entry name onto a path within outDir, applying the strip-components
+// and subdir options. Returns false if the entry should be skipped.
+func resolveEntry(outDir string, name string, opts *Options) (string, bool, error) {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+
+	parts := strings.Split(name, "/")
+	if len(parts) <= opts.StripComponents {
+		return "", false, nil
+	}
+	name = path.Join(parts[opts.StripComponents:]...)
+
+	if opts.Subdir != "" {
+		subdir := strings.Trim(path.Clean(opts.Subdir), "/")
+		if name != subdir && !strings.HasPrefix(name, subdir+"/") {
+			return "", false, nil
+		}
+		name = strings.TrimPrefix(strings.TrimPrefix(name, subdir), "/")
+	}
+
+	if name == "" || name == "." {
+		return "", false, nil
+	}
+
+	target := filepath.Join(outDir, filepath.FromSlash(name))
+	if !isWithin(outDir, target) {
+		return "", false, fmt.Errorf(colour.Sprintf("archive entry ^1%s^R escapes ^3%s^R", name,
+			outDir))
+	}
+
+	return target, true, nil
+}
+
+func isWithin(dir string, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func writeFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+
+	if mode == 0 {
+		mode = 0644
+	}
+
+	os.Remove(target)
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, r)
+	return err
+}
+
+// writeSymlink creates a symlink at target pointing to link. The link is resolved from where the
+// symlink really ends up, since earlier symlinks in the archive may have moved it, and must stay
+// within outDir so later entries can't be written through it to anywhere else.
+func writeSymlink(outDir string, target string, link string) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+
+	dir, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+
+	root, err := filepath.EvalSymlinks(outDir)
+	if err != nil {
+		return err
+	}
+
+	resolved := filepath.Join(dir, filepath.FromSlash(link))
+	if filepath.IsAbs(link) || !isWithin(root, resolved) {
+		return fmt.Errorf(colour.Sprintf("archive symlink ^1%s^R -> ^1%s^R escapes ^3%s^R",
+			filepath.Base(target), link, outDir))
+	}
+
+	os.Remove(target)
+	return os.Symlink(link, target)
+}
@@ -0,0 +1,406 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type entry struct {
+	name     string
+	typeflag byte
+	body     string
+	link     string
+}
+
+func file(name string, body string) entry {
+	return entry{name: name, typeflag: tar.TypeReg, body: body}
+}
+
+func dir(name string) entry {
+	return entry{name: name, typeflag: tar.TypeDir}
+}
+
+func symlink(name string, link string) entry {
+	return entry{name: name, typeflag: tar.TypeSymlink, link: link}
+}
+
+func hardlink(name string, link string) entry {
+	return entry{name: name, typeflag: tar.TypeLink, link: link}
+}
+
+func tarball(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.link,
+			Mode:     0755,
+			Size:     int64(len(e.body)),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func zipball(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name}
+		body := e.body
+		switch e.typeflag {
+		case tar.TypeDir:
+			header.Name = strings.TrimSuffix(e.name, "/") + "/"
+		case tar.TypeSymlink:
+			header.SetMode(os.ModeSymlink | 0777)
+			body = e.link
+		default:
+			header.SetMode(0755)
+		}
+
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+// listFiles returns every path under dir, relative to it, with symlinks marked by their target
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	var files []string
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || file == dir {
+			return err
+		}
+
+		rel, _ := filepath.Rel(dir, file)
+		rel = filepath.ToSlash(rel)
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, _ := os.Readlink(file)
+			rel += " -> " + link
+		} else if info.IsDir() {
+			rel += "/"
+		}
+
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+func TestDetectFormat(t *testing.T) {
+	tgz := gzipped(t, tarball(t, file("bin/tool", "tool")))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		sniff    bool
+		expected Format
+	}{
+		{"tool.tar.gz", tgz, false, TarGzip},
+		{"tool.TGZ", tgz, false, TarGzip},
+		{"tool.tar.xz", nil, false, TarXz},
+		{"tool.tbz2", nil, false, TarBzip2},
+		{"tool.tar", nil, false, Tar},
+		{"tool.zip", nil, false, Zip},
+		{"tool.gz", nil, false, Gzip},
+		{"tool", tgz, false, Unknown},
+		{"tool", tgz, true, TarGzip},
+		{"tool", tarball(t, file("tool", "tool")), true, Tar},
+		{"tool", gzipped(t, []byte("#!/bin/sh\n")), true, Gzip},
+		{"tool", []byte("#!/bin/sh\n"), true, Unknown},
+		{"tool.jar", zipball(t, file("META-INF/MANIFEST.MF", "")), false, Unknown},
+		{"tool.jar", zipball(t, file("META-INF/MANIFEST.MF", "")), true, Zip},
+	}
+
+	for _, test := range tests {
+		format, err := DetectFormat(writeTemp(t, test.name, test.data), test.sniff)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if format != test.expected {
+			t.Errorf("DetectFormat(%s, sniff=%t) = %q, expected %q", test.name, test.sniff, format,
+				test.expected)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	entries := []entry{
+		dir("tool-1.0/"),
+		file("tool-1.0/README", "readme"),
+		dir("tool-1.0/bin/"),
+		file("tool-1.0/bin/tool", "tool"),
+		symlink("tool-1.0/bin/t", "tool"),
+	}
+
+	tests := []struct {
+		name     string
+		opts     *Options
+		expected []string
+	}{
+		{
+			name: "everything",
+			opts: nil,
+			expected: []string{"tool-1.0/", "tool-1.0/README", "tool-1.0/bin/",
+				"tool-1.0/bin/t -> tool", "tool-1.0/bin/tool"},
+		},
+		{
+			name:     "strip components",
+			opts:     &Options{StripComponents: 1},
+			expected: []string{"README", "bin/", "bin/t -> tool", "bin/tool"},
+		},
+		{
+			name:     "strip every component",
+			opts:     &Options{StripComponents: 3},
+			expected: nil,
+		},
+		{
+			name:     "subdir",
+			opts:     &Options{Subdir: "tool-1.0/bin"},
+			expected: []string{"t -> tool", "tool"},
+		},
+		{
+			name:     "subdir after stripping components",
+			opts:     &Options{StripComponents: 1, Subdir: "/bin/"},
+			expected: []string{"t -> tool", "tool"},
+		},
+		{
+			name:     "missing subdir",
+			opts:     &Options{Subdir: "lib"},
+			expected: nil,
+		},
+	}
+
+	archives := []struct {
+		name   string
+		format Format
+		data   []byte
+	}{
+		{"tar", Tar, tarball(t, entries...)},
+		{"tar.gz", TarGzip, gzipped(t, tarball(t, entries...))},
+		{"zip", Zip, zipball(t, entries...)},
+	}
+
+	for _, archive := range archives {
+		for _, test := range tests {
+			t.Run(archive.name+"/"+test.name, func(t *testing.T) {
+				filePath := writeTemp(t, "tool."+archive.name, archive.data)
+				outDir := t.TempDir()
+
+				if err := Extract(filePath, archive.format, outDir, "tool", test.opts); err != nil {
+					t.Fatal(err)
+				}
+
+				files := listFiles(t, outDir)
+				if strings.Join(files, ",") != strings.Join(test.expected, ",") {
+					t.Errorf("extracted %q, expected %q", files, test.expected)
+				}
+			})
+		}
+	}
+}
+
+func TestExtractGzip(t *testing.T) {
+	filePath := writeTemp(t, "tool.gz", gzipped(t, []byte("#!/bin/sh\n")))
+	outDir := t.TempDir()
+
+	if err := Extract(filePath, Gzip, outDir, "tool", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "tool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "#!/bin/sh\n" {
+		t.Errorf("unexpected contents %q", data)
+	}
+}
+
+func TestExtractStaysWithinOutDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []entry
+		expected []string
+		fails    bool
+	}{
+		{
+			name:     "parent directory entries",
+			entries:  []entry{file("../evil", "evil"), file("bin/../../../evil2", "evil")},
+			expected: []string{"evil", "evil2"},
+		},
+		{
+			name:     "absolute entries",
+			entries:  []entry{file("/evil", "evil"), file("/etc/evil", "evil")},
+			expected: []string{"etc/", "etc/evil", "evil"},
+		},
+		{
+			name:    "symlink out of the archive",
+			entries: []entry{symlink("evil", "../outside")},
+			fails:   true,
+		},
+		{
+			name:    "absolute symlink",
+			entries: []entry{symlink("evil", "/etc/passwd")},
+			fails:   true,
+		},
+		{
+			name: "symlink escaping through an earlier symlink",
+			entries: []entry{
+				dir("a/b/"),
+				symlink("a/b/up", ".."),
+				symlink("a/b/up/up", ".."),
+				symlink("a/b/up/up/evil", "../outside"),
+			},
+			fails: true,
+		},
+		{
+			name:     "symlink within the archive",
+			entries:  []entry{file("bin/tool", "tool"), symlink("tool", "bin/tool")},
+			expected: []string{"bin/", "bin/tool", "tool -> bin/tool"},
+		},
+		{
+			name:    "hard link out of the archive",
+			entries: []entry{hardlink("evil", "../outside")},
+			fails:   true,
+		},
+		{
+			name:     "hard link within the archive",
+			entries:  []entry{file("tool", "tool"), hardlink("t", "tool")},
+			expected: []string{"t", "tool"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			outside := filepath.Join(root, "outside")
+			if err := os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			outDir := filepath.Join(root, "out")
+			if err := os.Mkdir(outDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			filePath := writeTemp(t, "tool.tar", tarball(t, test.entries...))
+			err := Extract(filePath, Tar, outDir, "tool", nil)
+			if test.fails != (err != nil) {
+				t.Fatalf("expected failure=%t, got error %v", test.fails, err)
+			}
+
+			entries, _ := os.ReadDir(root)
+			if len(entries) != 2 {
+				t.Errorf("expected only out/ and outside in %s, found %d entries", root,
+					len(entries))
+			}
+
+			info, _ := os.Stat(outside)
+			if data, _ := os.ReadFile(outside); string(data) != "outside" {
+				t.Errorf("file outside the output directory was modified: %q", data)
+			}
+			if links, err := filepath.Glob(filepath.Join(outDir, "*")); err == nil {
+				for _, link := range links {
+					if linkInfo, err := os.Stat(link); err == nil && os.SameFile(info, linkInfo) {
+						t.Errorf("%s links to a file outside the output directory", link)
+					}
+				}
+			}
+
+			if !test.fails {
+				files := listFiles(t, outDir)
+				if strings.Join(files, ",") != strings.Join(test.expected, ",") {
+					t.Errorf("extracted %q, expected %q", files, test.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractZipStaysWithinOutDir(t *testing.T) {
+	root := t.TempDir()
+	outDir := filepath.Join(root, "out")
+	if err := os.Mkdir(outDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	filePath := writeTemp(t, "tool.zip", zipball(t, file("../evil", "evil"), file("/abs", "abs")))
+	if err := Extract(filePath, Zip, outDir, "tool", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if files := listFiles(t, outDir); strings.Join(files, ",") != "abs,evil" {
+		t.Errorf("unexpected files %q", files)
+	}
+
+	filePath = writeTemp(t, "link.zip", zipball(t, symlink("evil", "../../outside")))
+	if err := Extract(filePath, Zip, outDir, "tool", nil); err == nil {
+		t.Error("expected a symlink out of the archive to fail")
+	}
+}
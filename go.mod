@@ -7,14 +7,15 @@ require (
 	github.com/alecthomas/hcl v0.1.13
 	github.com/alecthomas/kong v0.2.17
 	github.com/alecthomas/kong-hcl v1.0.1
-	github.com/blang/semver/v4 v4.0.0 // indirect
-	github.com/go-git/go-git/v5 v5.4.2 // indirect
-	github.com/imdario/mergo v0.3.12 // indirect
+	github.com/blang/semver/v4 v4.0.0
+	github.com/go-git/go-git/v5 v5.4.2
+	github.com/imdario/mergo v0.3.12
 	github.com/kardianos/osext v0.0.0-20190222173326-2bc1f35cddc0
-	github.com/mattn/go-isatty v0.0.13 // indirect
+	github.com/mattn/go-isatty v0.0.13
 	github.com/pkg/errors v0.9.1
 	github.com/posener/complete v1.2.3
 	github.com/sirupsen/logrus v1.8.1
+	github.com/ulikunitz/xz v0.5.10
 	github.com/valyala/fasttemplate v1.2.1
 	github.com/willabides/kongplete v0.2.0
 	golang.org/x/sys v0.0.0-20210502180810-71e4cd670f79 // indirect
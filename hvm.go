@@ -2,33 +2,45 @@ package hvm
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/josephschmitt/hvm/paths"
 	"github.com/josephschmitt/hvm/repos"
 
 	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/cache"
+	"github.com/josephschmitt/hvm/checksum"
 	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/download"
+	"github.com/josephschmitt/hvm/extract"
+	"github.com/josephschmitt/hvm/lockfile"
 	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/mirror"
+	"github.com/josephschmitt/hvm/offline"
+	"github.com/josephschmitt/hvm/store"
 	"github.com/josephschmitt/hvm/tmpl"
 	log "github.com/sirupsen/logrus"
 )
 
 func Link(ctx *context.Context, names []string, force bool) error {
-	loader := repos.NewGitRepoLoader("", "")
-	loader.Update()
+	if err := repos.UpdateStale(ctx.RepoUpdateInterval); err != nil {
+		log.Error(err)
+	}
 	exitCode := 0
 
 	for _, name := range names {
-		if !loader.HasPackage(name) {
-			log.Errorf(colour.Sprintf("Package \"^3%s^R\" not found in package repository at ^6%s^R",
-				name, loader.GetLocation()))
+		if _, err := repos.FindPackage(name); err != nil {
+			log.Error(err)
 			exitCode++
 			continue
 		}
@@ -39,8 +51,10 @@ func Link(ctx *context.Context, names []string, force bool) error {
 			for k := range manConf.Bins {
 				bins = append(bins, k)
 			}
-		} else {
-			bins = append(bins, name)
+		}
+
+		if len(bins) == 0 {
+			bins = append(bins, repos.PackageName(name))
 		}
 
 		for _, bin := range bins {
@@ -127,20 +141,18 @@ func UnLink(ctx *context.Context, names []string, force bool) error {
 }
 
 func Run(ctx *context.Context, name string, bin string, args ...string) error {
-	manCtx := manifest.NewManifestContext(name, ctx.Use[bin])
-
-	man, err := manifest.NewPackageManfiest(name, manCtx, ctx.Packages[name])
+	man, manCtx, err := resolvePackage(ctx, name, ctx.Use[bin])
 	if err != nil {
 		return err
 	}
 
 	if !hasPackageLocally(manCtx.OutputDir, man.Bins[bin]) {
-		if err := DownloadAndExtractPackage(ctx, man, manCtx); err != nil {
+		if err := InstallPackage(ctx, man, manCtx); err != nil {
 			return err
 		}
 	}
 
-	cmdName := filepath.Join(manCtx.OutputDir, man.Bins[bin])
+	cmdName := binLocation(manCtx.OutputDir, man.Bins[bin])
 	if man.Exec != "" {
 		args = append([]string{cmdName}, args...)
 		cmdName = man.Exec
@@ -162,14 +174,208 @@ func Run(ctx *context.Context, name string, bin string, args ...string) error {
 	return nil
 }
 
+// Install downloads and extracts packages without running them. Each spec is a package name,
+// optionally suffixed with "@version". With no specs, every package in the merged config is
+// installed.
+func Install(ctx *context.Context, specs []string) error {
+	if len(specs) == 0 {
+		specs = ctx.ConfiguredPackages()
+	}
+
+	if len(specs) == 0 {
+		log.Warnf("No packages found in config.hcl to install\n")
+		return nil
+	}
+
+	failed := 0
+	var unavailable []string
+	for _, spec := range specs {
+		name, version := manifest.ParsePackageSpec(spec)
+		if version == "" {
+			version = ctx.Use[name]
+		}
+
+		man, manCtx, err := resolvePackage(ctx, name, version)
+		if err != nil {
+			log.Errorf(colour.Sprintf("Failed to resolve ^3%s^R: %s", spec, err))
+			failed++
+			continue
+		}
+
+		if store.IsInstalled(manCtx.OutputDir) {
+			colour.Printf("^3%s@%s^R already installed\n", man.Name, man.Version)
+			continue
+		}
+
+		if err := InstallPackage(ctx, man, manCtx); offline.IsError(err) {
+			unavailable = append(unavailable, fmt.Sprintf("%s@%s", man.Name, man.Version))
+			continue
+		} else if err != nil {
+			log.Errorf(colour.Sprintf("Failed to install ^3%s@%s^R: %s", man.Name, man.Version, err))
+			failed++
+			continue
+		}
+
+		colour.Printf("^2Installed^R ^3%s@%s^R to ^6%s^R\n", man.Name, man.Version, manCtx.OutputDir)
+	}
+
+	// Everything that needs fetching is listed together, rather than failing on the first
+	if len(unavailable) > 0 {
+		if failed > 0 {
+			log.Errorf("%d of %d package(s) failed to install", failed, len(specs))
+		}
+		return offline.Missing(unavailable)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d package(s) failed to install", failed, len(specs))
+	}
+
+	return nil
+}
+
+// Verify checks an installed package's bins and runs its manifest's test command against it
+func Verify(ctx *context.Context, name string) error {
+	man, manCtx, err := resolvePackage(ctx, name, ctx.Use[name])
+	if err != nil {
+		return err
+	}
+
+	if !store.IsInstalled(manCtx.OutputDir) {
+		return fmt.Errorf(colour.Sprintf("^3%s@%s^R is not installed", man.Name, man.Version))
+	}
+
+	if err := verifyBins(man, manCtx, manCtx.OutputDir); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(man.Test) == "" {
+		log.Warnf(colour.Sprintf("No test declared in manifest for ^3%s^R, only checked bins\n",
+			man.Name))
+	} else {
+		out, err := TestPackage(man, manCtx, manCtx.OutputDir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(string(out))
+	}
+
+	colour.Printf("^2Verified^R ^3%s@%s^R at ^6%s^R\n", man.Name, man.Version, manCtx.OutputDir)
+	return nil
+}
+
+// resolvePackage renders the manifest of a package for this platform, holding it to the project's
+// hvm.lock if there is one
+func resolvePackage(
+	ctx *context.Context,
+	name string,
+	version string,
+) (*manifest.PackageManifest, *manifest.PackageManifestContext, error) {
+	version, overrides, err := packageVersion(ctx, name, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manCtx := manifest.NewManifestContext(name, version)
+
+	man, err := manifest.NewPackageManfiest(name, manCtx, overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := applyLock(ctx.Lock, man, manCtx); err != nil {
+		return nil, nil, err
+	}
+
+	return man, manCtx, nil
+}
+
+// applyLock refuses manifests that deviate from the lockfile, and pins the download to the locked
+// checksum so the same bits get installed everywhere
+func applyLock(
+	lock *lockfile.Lockfile,
+	man *manifest.PackageManifest,
+	manCtx *manifest.PackageManifestContext,
+) error {
+	err := lock.Check(man.Name, manCtx.Platform, man.Version, man.Source, man.Bins)
+	if err != nil {
+		return err
+	}
+
+	_, plat := lock.Get(man.Name, manCtx.Platform)
+	if plat == nil || plat.Checksum == "" {
+		return nil
+	}
+
+	sum, err := checksum.Parse(plat.Checksum)
+	if err != nil {
+		return err
+	}
+
+	man.Sha256 = nil
+	man.Sha512 = nil
+	switch sum.Algorithm {
+	case checksum.SHA256:
+		man.Sha256 = map[string]string{manCtx.Platform: sum.Digest}
+	case checksum.SHA512:
+		man.Sha512 = map[string]string{manCtx.Platform: sum.Digest}
+	}
+
+	// Catch installs that happened before the lockfile was written, or with different bits
+	if receipt, err := store.ReadReceipt(manCtx.OutputDir); err == nil {
+		if installed, err := checksum.Parse(receipt.Checksum); err == nil &&
+			installed.Algorithm == sum.Algorithm && installed.Digest != sum.Digest {
+			return fmt.Errorf(colour.Sprintf("installed ^3%s@%s^R at ^6%s^R doesn't match the "+
+				"checksum in ^6%s^R. Run ^5hvm uninstall %s@%s^R to reinstall it.", man.Name,
+				man.Version, manCtx.OutputDir, lock.GetPath(), man.Name, man.Version))
+		}
+	}
+
+	return nil
+}
+
 func GetPackageRepos(ctx *context.Context) error {
-	loader := repos.NewGitRepoLoader("", "")
-	return loader.Get()
+	for _, loader := range repos.Loaders() {
+		if err := repos.Get(loader); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func UpdatePackagesRepos(ctx *context.Context) error {
-	loader := repos.NewGitRepoLoader("", "")
-	return loader.Update()
+	for _, loader := range repos.Loaders() {
+		if err := repos.Update(loader); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InstallPackage downloads and extracts a package while holding its install lock, so concurrent
+// hvm processes don't race each other. If another process installed the package while this one was
+// waiting on the lock, its result is reused.
+func InstallPackage(
+	ctx *context.Context,
+	man *manifest.PackageManifest,
+	manCtx *manifest.PackageManifestContext,
+) error {
+	lock, err := store.LockPackage(man.Name, man.Version)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if store.IsInstalled(manCtx.OutputDir) {
+		log.Debugf(colour.Sprintf("^3%s@%s^R already installed at ^6%s^R\n", man.Name, man.Version,
+			manCtx.OutputDir))
+		return nil
+	}
+
+	return DownloadAndExtractPackage(ctx, man, manCtx)
 }
 
 func DownloadAndExtractPackage(
@@ -180,9 +386,6 @@ func DownloadAndExtractPackage(
 	name := man.Name
 	version := man.Version
 	source := man.Source
-	extract := man.Extract
-
-	log.Infof(colour.Sprintf("Downloading ^3%s@%s^R from ^2%s^R...\n", name, version, source))
 
 	if version == "" {
 		return fmt.Errorf("no version set for package \"%s\", please set a version in config.hcl",
@@ -192,37 +395,28 @@ func DownloadAndExtractPackage(
 		return fmt.Errorf("no source URL set for package \"%s\"", name)
 	}
 
-	resp, err := http.Get(source)
+	sum, err := man.GetChecksum(manCtx.Platform)
 	if err != nil {
 		return err
-	} else if resp.StatusCode >= 400 {
-		return fmt.Errorf(colour.Sprintf("failed to download ^3%s@%s^R from ^1%s^R...", name,
-			version, source))
 	}
-	defer resp.Body.Close()
 
-	dlFilePath := filepath.Join(paths.AppPaths.TempDirectory, filepath.Base(source))
-	err = os.MkdirAll(filepath.Dir(dlFilePath), os.ModePerm)
+	dlFilePath, observed, err := fetchMirrored(ctx, name, version, source, sum)
 	if err != nil {
 		return err
 	}
 
-	dl, err := os.Create(dlFilePath)
+	// Install into a staging directory first so a failed or interrupted install never leaves a
+	// half-populated output directory behind
+	outDir, err := store.NewStagingDir(name, version)
 	if err != nil {
 		return err
 	}
-	defer dl.Close()
+	defer os.RemoveAll(outDir)
 
-	_, err = io.Copy(dl, resp.Body)
-	if err != nil {
-		return err
-	}
-
-	log.Debugf(colour.Sprintf("Downloaded file to ^6%s^R\n", dlFilePath))
-
-	outDir := manCtx.OutputDir
-
-	err = os.MkdirAll(outDir, os.ModePerm)
+	// Setting any of the extractor's options opts a download without an archive extension into
+	// being recognized by its contents
+	sniff := man.StripComponents > 0 || man.Subdir != ""
+	format, err := extract.DetectFormat(dlFilePath, sniff)
 	if err != nil {
 		return err
 	}
@@ -231,16 +425,18 @@ func DownloadAndExtractPackage(
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
-	if extract != "" {
-		extractCmdParts := strings.Split(extract, " ")
+	if man.Extract != "" {
+		extractCmdParts := strings.Split(stagedCommand(man.Extract, manCtx, outDir), " ")
 		extractCmd := extractCmdParts[0]
 		extractArgs := extractCmdParts[1:]
 
-		log.Debugf("Extract: %s", extract)
+		log.Debugf("Extract: %s", man.Extract)
 
+		// Commands that extract into the current directory extract into the package
 		cmd := exec.Command(extractCmd, extractArgs...)
-		cmd.Dir = paths.AppPaths.TempDirectory
+		cmd.Dir = outDir
 		cmd.Stdout = nil
 		cmd.Stderr = os.Stderr
 		cmd.Stdin = file
@@ -250,6 +446,17 @@ func DownloadAndExtractPackage(
 		}
 
 		log.Debugf(colour.Sprintf("Successfully extracted to ^3%s^R\n", outDir))
+	} else if format != extract.Unknown {
+		opts := &extract.Options{
+			StripComponents: man.StripComponents,
+			Subdir:          man.Subdir,
+		}
+
+		if err := extract.Extract(dlFilePath, format, outDir, name, opts); err != nil {
+			return err
+		}
+
+		log.Debugf(colour.Sprintf("Successfully extracted ^5%s^R archive to ^3%s^R\n", format, outDir))
 	} else {
 		outputPath := filepath.Join(outDir, name)
 		outputFile, err := os.Create(outputPath)
@@ -270,13 +477,317 @@ func DownloadAndExtractPackage(
 		log.Debugf(colour.Sprintf("No extract in manifest, moved download to ^3%s^R\n", outDir))
 	}
 
+	if err := verifyInstall(man, manCtx, outDir); err != nil {
+		return fmt.Errorf(colour.Sprintf("failed to install ^3%s@%s^R: %s", name, version, err))
+	}
+
+	receipt := &store.Receipt{
+		Name:      name,
+		Version:   version,
+		Source:    source,
+		Checksum:  observed.String(),
+		Installed: time.Now(),
+	}
+	if sum != nil {
+		receipt.Checksum = sum.String()
+	}
+
+	if err := store.WriteReceipt(outDir, receipt); err != nil {
+		return err
+	}
+
+	if err := store.Commit(outDir, manCtx.OutputDir); err != nil {
+		return err
+	}
+
+	log.Debugf(colour.Sprintf("Installed ^3%s@%s^R to ^6%s^R\n", name, version, manCtx.OutputDir))
+
+	return nil
+}
+
+// fetchMirrored fetches a package's source through the first mirror that applies to it, falling
+// back to the original source if the mirror doesn't have it
+func fetchMirrored(
+	ctx *context.Context,
+	name string,
+	version string,
+	source string,
+	sum *checksum.Checksum,
+) (string, *checksum.Checksum, error) {
+	mirrored, m := mirror.Rewrite(ctx.Mirrors, name, source)
+	if m == nil {
+		return fetchSource(ctx, name, version, source, sum)
+	}
+
+	log.Debugf(colour.Sprintf("Rewrote ^2%s^R to ^2%s^R using mirror ^3%s^R\n", source, mirrored,
+		m.Name))
+
+	dlFilePath, observed, err := fetchSource(ctx, name, version, mirrored, sum)
+
+	var statusErr *download.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		log.Warnf(colour.Sprintf("Mirror ^3%s^R doesn't have ^3%s@%s^R at ^1%s^R, downloading it "+
+			"from ^2%s^R", m.Name, name, version, mirrored, source))
+		return fetchSource(ctx, name, version, source, sum)
+	} else if offline.IsError(err) {
+		// It may have been cached when downloaded from the original source
+		return fetchSource(ctx, name, version, source, sum)
+	}
+
+	return dlFilePath, observed, err
+}
+
+// fetchSource returns the path to a verified download of a package's source, along with its
+// sha256. Downloads are kept in the download cache, and reused from it when possible. Interrupted
+// downloads are resumed, here or by the next install.
+func fetchSource(
+	ctx *context.Context,
+	name string,
+	version string,
+	source string,
+	sum *checksum.Checksum,
+) (string, *checksum.Checksum, error) {
+	if entry, ok := cache.Lookup(source, sum); ok {
+		observed, err := verifyDownload(entry.Path(), sum)
+		if err == nil {
+			log.Infof(colour.Sprintf("Using cached download of ^3%s@%s^R from ^2%s^R\n", name,
+				version, source))
+			return entry.Path(), observed, nil
+		}
+
+		log.Warnf(colour.Sprintf("Cached download of ^3%s@%s^R doesn't match, downloading it "+
+			"again: %s", name, version, err))
+		if err := cache.Evict(entry); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if offline.Enabled() {
+		return "", nil, &offline.Error{What: fmt.Sprintf("%s@%s", name, version), Source: source}
+	}
+
+	log.Infof(colour.Sprintf("Downloading ^3%s@%s^R from ^2%s^R...\n", name, version, source))
+
+	partial, err := cache.PartialFile(source)
+	if err != nil {
+		return "", nil, err
+	}
+
+	_, statErr := os.Stat(partial)
+	resumed := statErr == nil
+
+	hashes, err := newDownloadHashes(sum)
+	if err != nil {
+		return "", nil, err
+	}
+
+	opts := &download.Options{
+		Label:    fmt.Sprintf("%s@%s", name, version),
+		Attempts: ctx.DownloadAttempts,
+		Timeout:  ctx.DownloadTimeout,
+		Hashes:   hashes.list(),
+	}
+
+	if err := download.Fetch(source, partial, opts); err != nil {
+		// Keep what was downloaded so far around to resume, unless there's nothing to resume
+		if info, statErr := os.Stat(partial); statErr == nil && info.Size() == 0 {
+			os.Remove(partial)
+		}
+
+		return "", nil, fmt.Errorf("%s: %w", colour.Sprintf("failed to download ^3%s@%s^R from "+
+			"^1%s^R", name, version, source), err)
+	}
+
+	log.Debugf(colour.Sprintf("Downloaded file to ^6%s^R\n", partial))
+
+	observed, err := hashes.verify()
+	if err != nil {
+		os.Remove(partial)
+
+		// The start of the file may have come from an older download of a different file
+		if resumed {
+			log.Warnf(colour.Sprintf("Resumed download of ^3%s@%s^R doesn't match, downloading it "+
+				"again: %s", name, version, err))
+			return fetchSource(ctx, name, version, source, sum)
+		}
+
+		return "", nil, fmt.Errorf(colour.Sprintf("refusing to install ^3%s@%s^R from ^1%s^R: %s",
+			name, version, source, err))
+	}
+
+	entry, err := cache.Store(source, partial, observed)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return entry.Path(), observed, nil
+}
+
+// verifyDownload checks a downloaded file against its declared checksum, if any, returning its
+// sha256. Used for files that are already on disk, like cached downloads.
+func verifyDownload(file string, sum *checksum.Checksum) (*checksum.Checksum, error) {
+	hashes, err := newDownloadHashes(sum)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(hashes, f); err != nil {
+		return nil, err
+	}
+
+	return hashes.verify()
+}
+
+// downloadHashes digests a download as it's written. A sha256 is always taken so the install can
+// be pinned in hvm.lock, even without a declared checksum.
+type downloadHashes struct {
+	sum      *checksum.Checksum
+	declared hash.Hash
+	observed hash.Hash
+}
+
+func newDownloadHashes(sum *checksum.Checksum) (*downloadHashes, error) {
+	hashes := &downloadHashes{sum: sum, observed: sha256.New()}
+	if sum != nil {
+		h, err := sum.NewHash()
+		if err != nil {
+			return nil, err
+		}
+		hashes.declared = h
+	}
+
+	return hashes, nil
+}
+
+// list returns every hash being taken, to be fed the download
+func (hashes *downloadHashes) list() []hash.Hash {
+	if hashes.declared == nil {
+		return []hash.Hash{hashes.observed}
+	}
+
+	return []hash.Hash{hashes.observed, hashes.declared}
+}
+
+func (hashes *downloadHashes) Write(p []byte) (int, error) {
+	for _, h := range hashes.list() {
+		h.Write(p)
+	}
+
+	return len(p), nil
+}
+
+// verify checks what was written against the declared checksum, if any, returning its sha256
+func (hashes *downloadHashes) verify() (*checksum.Checksum, error) {
+	if hashes.sum != nil {
+		if err := hashes.sum.Verify(hashes.declared); err != nil {
+			return nil, err
+		}
+
+		log.Debugf(colour.Sprintf("Verified ^5%s^R\n", hashes.sum))
+	}
+
+	return checksum.FromHash(checksum.SHA256, hashes.observed), nil
+}
+
+// verifyInstall checks that an install has every declared bin, and that the manifest's test
+// command (if any) passes
+func verifyInstall(
+	man *manifest.PackageManifest,
+	manCtx *manifest.PackageManifestContext,
+	outDir string,
+) error {
+	if err := verifyBins(man, manCtx, outDir); err != nil {
+		return err
+	}
+
+	out, err := TestPackage(man, manCtx, outDir)
+	if err != nil {
+		return err
+	}
+
+	if len(out) > 0 {
+		log.Debugf(colour.Sprintf("Test output for ^3%s@%s^R:\n%s", man.Name, man.Version, out))
+	}
+
 	return nil
 }
 
+func verifyBins(
+	man *manifest.PackageManifest,
+	manCtx *manifest.PackageManifestContext,
+	outDir string,
+) error {
+	for bin, binPath := range man.Bins {
+		info, err := os.Stat(binLocation(outDir, stagedCommand(binPath, manCtx, outDir)))
+		if err != nil {
+			return fmt.Errorf(colour.Sprintf("bin ^3%s^R not found at ^1%s^R", bin, binPath))
+		}
+
+		// Bins run through an exec command (e.g. a jar run by java) don't need to be executable
+		if man.Exec == "" && (info.IsDir() || info.Mode().Perm()&0111 == 0) {
+			return fmt.Errorf(colour.Sprintf("bin ^3%s^R at ^1%s^R is not executable", bin, binPath))
+		}
+	}
+
+	return nil
+}
+
+// TestPackage runs the manifest's rendered test command from within outDir, returning its combined
+// output. A non-zero exit is returned as an error that includes the output.
+func TestPackage(
+	man *manifest.PackageManifest,
+	manCtx *manifest.PackageManifestContext,
+	outDir string,
+) ([]byte, error) {
+	// A test that's blank, or renders to nothing, has nothing to run
+	testCmdParts := strings.Fields(stagedCommand(man.Test, manCtx, outDir))
+	if len(testCmdParts) == 0 {
+		return nil, nil
+	}
+
+	log.Debugf("Test: %s", man.Test)
+
+	cmd := exec.Command(testCmdParts[0], testCmdParts[1:]...)
+	cmd.Dir = outDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf(colour.Sprintf("test command ^5%s^R failed: %s\n%s", man.Test, err,
+			out))
+	}
+
+	return out, nil
+}
+
+// stagedCommand points a rendered manifest command at the staging directory instead of the final
+// output directory it was rendered with via ${output}
+func stagedCommand(command string, manCtx *manifest.PackageManifestContext, stagingDir string) string {
+	return strings.ReplaceAll(command, manCtx.OutputDir, stagingDir)
+}
+
+// binLocation returns the path to a bin installed in dir. Bins are usually relative to the package
+// directory, but may also be absolute, e.g. when rendered from ${output}.
+func binLocation(dir string, binPath string) string {
+	if filepath.IsAbs(binPath) {
+		return binPath
+	}
+
+	return filepath.Join(dir, binPath)
+}
+
 func hasPackageLocally(outdir string, bin string) bool {
-	binPath := filepath.Join(outdir, bin)
+	if !store.IsInstalled(outdir) {
+		return false
+	}
+
+	binPath := binLocation(outdir, bin)
 
-	if _, err := os.ReadFile(binPath); err == nil {
+	if _, err := os.Stat(binPath); err == nil {
 		return true
 	}
 
@@ -0,0 +1,457 @@
+package hvm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/josephschmitt/hvm/cache"
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/mirror"
+	"github.com/josephschmitt/hvm/offline"
+	"github.com/josephschmitt/hvm/paths"
+	"github.com/josephschmitt/hvm/repos"
+	"github.com/josephschmitt/hvm/store"
+)
+
+// usePaths points hvm at a temporary home directory for the rest of the test
+func usePaths(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	original := paths.AppPaths
+	paths.AppPaths = paths.NewPathsFromHome(dir, filepath.Join(dir, "home"),
+		filepath.Join(dir, "tmp"))
+	t.Cleanup(func() { paths.AppPaths = original })
+}
+
+// useRepo makes manifests, keyed by package name, available from a local directory standing in for
+// the default package repository, so it's never cloned. Returns the directory.
+func useRepo(t *testing.T, manifests map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, manifest := range manifests {
+		if err := os.WriteFile(filepath.Join(dir, name+".hcl"), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repos.Configure([]*repos.Repository{{
+		Name: paths.PackageRepository,
+		Type: repos.LocalRepository,
+		URL:  dir,
+	}})
+	t.Cleanup(func() { repos.Configure(nil) })
+
+	return dir
+}
+
+// serve serves body at every path, returning the server's URL
+func serve(t *testing.T, body []byte) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func installFrom(
+	t *testing.T,
+	source string,
+	options manifest.PackageManifestOptions,
+) (*manifest.PackageManifestContext, error) {
+	t.Helper()
+
+	options.Version = "1.0.0"
+	options.Source = source
+
+	man := &manifest.PackageManifest{Name: "tool", PackageManifestOptions: options}
+	manCtx := manifest.NewPlatformManifestContext("tool", options.Version, runtime.GOOS,
+		runtime.GOARCH)
+
+	return manCtx, DownloadAndExtractPackage(&context.Context{DownloadAttempts: 1}, man, manCtx)
+}
+
+func TestDownloadAndExtractPackageChecksum(t *testing.T) {
+	body := []byte("#!/bin/sh\necho tool\n")
+	digest := sha256.Sum256(body)
+
+	tests := []struct {
+		name   string
+		sha256 string
+		ok     bool
+	}{
+		{"no checksum", "", true},
+		{"matching checksum", hex.EncodeToString(digest[:]), true},
+		{"mismatched checksum", hex.EncodeToString(make([]byte, sha256.Size)), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usePaths(t)
+
+			options := manifest.PackageManifestOptions{}
+			if test.sha256 != "" {
+				options.Sha256 = map[string]string{"*": test.sha256}
+			}
+
+			source := serve(t, body) + "/tool"
+			manCtx, err := installFrom(t, source, options)
+			if test.ok != (err == nil) {
+				t.Fatalf("expected ok=%t, got error %v", test.ok, err)
+			}
+
+			entries, err := cache.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.ok {
+				if !store.IsInstalled(manCtx.OutputDir) {
+					t.Errorf("expected tool to be installed at %s", manCtx.OutputDir)
+				}
+				if len(entries) != 1 {
+					t.Errorf("expected the download to be cached, got %d entries", len(entries))
+				}
+				return
+			}
+
+			if _, err := os.Stat(manCtx.OutputDir); !os.IsNotExist(err) {
+				t.Errorf("expected nothing installed at %s", manCtx.OutputDir)
+			}
+			if len(entries) != 0 {
+				t.Errorf("expected nothing cached, got %d entries", len(entries))
+			}
+
+			partial, err := cache.PartialFile(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(partial); !os.IsNotExist(err) {
+				t.Errorf("expected mismatched download %s to be deleted", partial)
+			}
+
+			staged, _ := os.ReadDir(store.StagingDir())
+			if len(staged) != 0 {
+				t.Errorf("expected nothing staged, found %d entries", len(staged))
+			}
+		})
+	}
+}
+
+func TestVerifyBins(t *testing.T) {
+	outDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(outDir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "bin", "tool"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "tool.jar"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The manifest is rendered against the final output directory, not the staging directory
+	manCtx := &manifest.PackageManifestContext{OutputDir: "/hvm-downloads/tool/1.0.0"}
+
+	tests := []struct {
+		name string
+		bins map[string]string
+		exec string
+		ok   bool
+	}{
+		{"relative bin", map[string]string{"tool": "bin/tool"}, "", true},
+		{"bin from ${output}", map[string]string{"tool": manCtx.OutputDir + "/bin/tool"}, "", true},
+		{"absolute bin", map[string]string{"tool": filepath.Join(outDir, "bin/tool")}, "", true},
+		{"missing bin", map[string]string{"tool": "tool"}, "", false},
+		{"missing bin from ${output}", map[string]string{"tool": manCtx.OutputDir + "/tool"}, "",
+			false},
+		{"not executable", map[string]string{"tool": "tool.jar"}, "", false},
+		{"run through exec", map[string]string{"tool": "tool.jar"}, "java -jar", true},
+		{"directory", map[string]string{"tool": "bin"}, "", false},
+	}
+
+	for _, test := range tests {
+		man := &manifest.PackageManifest{Name: "tool"}
+		man.Bins = test.bins
+		man.Exec = test.exec
+
+		err := verifyBins(man, manCtx, outDir)
+		if test.ok != (err == nil) {
+			t.Errorf("%s: expected ok=%t, got error %v", test.name, test.ok, err)
+		}
+	}
+}
+
+func TestTestPackage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test commands use unix tools")
+	}
+
+	outDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outDir, "tool"), []byte("tool"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	manCtx := &manifest.PackageManifestContext{OutputDir: "/hvm-downloads/tool/1.0.0"}
+
+	tests := []struct {
+		name   string
+		test   string
+		output string
+		ok     bool
+	}{
+		{"no test", "", "", true},
+		{"blank test", "  ", "", true},
+		{"passing test", "true", "", true},
+		{"failing test", "false", "", false},
+		{"runs in the package directory", "cat tool", "tool", true},
+		{"test against ${output}", "cat " + manCtx.OutputDir + "/tool", "tool", true},
+		{"failing test output", "cat missing", "missing", false},
+		{"missing command", "hvm-no-such-command", "", false},
+	}
+
+	for _, test := range tests {
+		man := &manifest.PackageManifest{Name: "tool"}
+		man.Test = test.test
+
+		out, err := TestPackage(man, manCtx, outDir)
+		if test.ok != (err == nil) {
+			t.Errorf("%s: expected ok=%t, got error %v", test.name, test.ok, err)
+		}
+		if !strings.Contains(string(out), test.output) {
+			t.Errorf("%s: expected output containing %q, got %q", test.name, test.output, out)
+		}
+		if err != nil && !strings.Contains(err.Error(), test.output) {
+			t.Errorf("%s: expected error to include the output %q, got %v", test.name,
+				test.output, err)
+		}
+	}
+}
+
+func TestDownloadAndExtractPackageRunsTest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test commands use unix tools")
+	}
+
+	for test, ok := range map[string]bool{"true": true, "false": false} {
+		usePaths(t)
+
+		manCtx, err := installFrom(t, serve(t, []byte("#!/bin/sh\n"))+"/tool",
+			manifest.PackageManifestOptions{Test: test})
+		if ok != (err == nil) {
+			t.Errorf("test %q: expected ok=%t, got error %v", test, ok, err)
+		}
+		if store.IsInstalled(manCtx.OutputDir) != ok {
+			t.Errorf("test %q: expected installed=%t", test, ok)
+		}
+	}
+}
+
+// tarball builds a gzipped tarball of files, keyed by path
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, body := range files {
+		header := &tar.Header{Name: name, Mode: 0755, Size: int64(len(body)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDownloadAndExtractPackageExtractCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("extract commands use unix tools")
+	}
+
+	source := serve(t, tarball(t, map[string]string{"bin/tool": "#!/bin/sh\n"})) + "/tool"
+
+	for _, extract := range []string{"tar -xz -C ${output}", "tar -xz"} {
+		usePaths(t)
+
+		// The manifest is rendered before it's installed, so ${output} is the final directory
+		outputDir := manifest.NewPlatformManifestContext("tool", "1.0.0", runtime.GOOS,
+			runtime.GOARCH).OutputDir
+		rendered := strings.ReplaceAll(extract, "${output}", outputDir)
+
+		manCtx, err := installFrom(t, source, manifest.PackageManifestOptions{
+			Extract: rendered,
+			Bins:    map[string]string{"tool": "bin/tool"},
+		})
+		if err != nil {
+			t.Errorf("%s: %s", extract, err)
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(manCtx.OutputDir, "bin", "tool")); err != nil {
+			t.Errorf("%s: expected bin/tool to be installed: %s", extract, err)
+		}
+		if !store.IsInstalled(manCtx.OutputDir) {
+			t.Errorf("%s: expected the package to be installed", extract)
+		}
+	}
+}
+
+func TestInstallOffline(t *testing.T) {
+	usePaths(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte("#!/bin/sh\n"))
+	}))
+	defer server.Close()
+
+	manifests := make(map[string]string)
+	for _, name := range []string{"cached", "missing", "other"} {
+		manifests[name] = "name = \"" + name + "\"\nversion = \"1.0.0\"\nsource = \"" +
+			server.URL + "/" + name + "\"\n"
+	}
+	useRepo(t, manifests)
+
+	ctx := &context.Context{
+		DownloadAttempts: 1,
+		Use:              map[string]string{},
+		Packages:         map[string]*manifest.PackageManifestOptions{},
+	}
+
+	// Download it once, leaving it in the download cache after it's uninstalled
+	if err := Install(ctx, []string{"cached"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Remove("cached", "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	offline.Enable(true)
+	defer offline.Enable(false)
+	before := atomic.LoadInt32(&requests)
+
+	err := Install(ctx, []string{"cached", "missing", "other"})
+	if err == nil {
+		t.Fatal("expected packages that aren't cached to fail offline")
+	}
+
+	for name, listed := range map[string]bool{"cached": false, "missing": true, "other": true} {
+		if strings.Contains(err.Error(), name+"@1.0.0") != listed {
+			t.Errorf("expected %s to be listed as missing: %t, got %s", name, listed, err)
+		}
+	}
+
+	if !store.IsInstalled(store.PackageDir("cached", "1.0.0")) {
+		t.Error("expected the cached package to be installed offline")
+	}
+	if after := atomic.LoadInt32(&requests); after != before {
+		t.Errorf("expected no downloads offline, got %d", after-before)
+	}
+}
+
+func TestDownloadAndExtractPackageMirror(t *testing.T) {
+	var origin, mirrored int32
+	originServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&origin, 1)
+		w.Write([]byte("origin"))
+	}))
+	defer originServer.Close()
+
+	mirrorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/empty/") {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/broken/") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		atomic.AddInt32(&mirrored, 1)
+		w.Write([]byte("mirror"))
+	}))
+	defer mirrorServer.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		contents string
+		ok       bool
+	}{
+		{"mirror has it", "/full/", "mirror", true},
+		{"mirror doesn't have it", "/empty/", "origin", true},
+		{"mirror fails", "/broken/", "", false},
+	}
+
+	for _, test := range tests {
+		usePaths(t)
+
+		ctx := &context.Context{
+			DownloadAttempts: 1,
+			Mirrors: []*mirror.Mirror{{
+				Name:   "proxy",
+				Prefix: originServer.URL + "/",
+				URL:    mirrorServer.URL + test.path,
+			}},
+		}
+
+		man := &manifest.PackageManifest{
+			Name: "tool",
+			PackageManifestOptions: manifest.PackageManifestOptions{
+				Version: "1.0.0",
+				Source:  originServer.URL + "/tool",
+			},
+		}
+		manCtx := manifest.NewPlatformManifestContext("tool", "1.0.0", runtime.GOOS,
+			runtime.GOARCH)
+
+		err := DownloadAndExtractPackage(ctx, man, manCtx)
+		if test.ok != (err == nil) {
+			t.Errorf("%s: expected ok=%t, got error %v", test.name, test.ok, err)
+			continue
+		} else if !test.ok {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(manCtx.OutputDir, "tool"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.contents {
+			t.Errorf("%s: expected the download from the %s, got %s", test.name, test.contents,
+				data)
+		}
+	}
+
+	if origin != 1 || mirrored != 1 {
+		t.Errorf("expected one download from each, got %d from the origin and %d from the mirror",
+			origin, mirrored)
+	}
+}
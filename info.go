@@ -0,0 +1,200 @@
+package hvm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/repos"
+	"github.com/josephschmitt/hvm/store"
+)
+
+type PackageInfo struct {
+	Name          string            `json:"name"`
+	Description   string            `json:"description,omitempty"`
+	Repository    string            `json:"repository"`
+	Manifest      string            `json:"manifest"`
+	Version       string            `json:"version"`
+	VersionSource string            `json:"versionSource"`
+	Platform      string            `json:"platform"`
+	Source        string            `json:"source"`
+	Checksum      string            `json:"checksum,omitempty"`
+	Bins          map[string]string `json:"bins"`
+	Exec          string            `json:"exec,omitempty"`
+	Extract       string            `json:"extract,omitempty"`
+	Test          string            `json:"test,omitempty"`
+	WithVersions  []string          `json:"withVersions,omitempty"`
+	WithPlatforms []string          `json:"withPlatforms,omitempty"`
+	Fallback      string            `json:"fallback,omitempty"`
+	Overrides     []string          `json:"overrides,omitempty"`
+	Path          string            `json:"path"`
+	Installed     bool              `json:"installed"`
+}
+
+// Info prints the manifest of a package rendered for this platform, along with where its version
+// and overrides came from
+func Info(ctx *context.Context, name string, version string, asJSON bool) error {
+	info, err := GetPackageInfo(ctx, name, version)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	var bins []string
+	for bin, path := range info.Bins {
+		bins = append(bins, fmt.Sprintf("%s -> %s", bin, path))
+	}
+	sort.Strings(bins)
+
+	extract := info.Extract
+	if extract == "" {
+		extract = "built-in"
+	}
+
+	platform := info.Platform
+	if info.Fallback != "" {
+		platform = fmt.Sprintf("%s (using %s)", info.Platform, info.Fallback)
+	}
+
+	installed := "no"
+	if info.Installed {
+		installed = "yes"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	rows := [][2]string{
+		{"Name", info.Name},
+		{"Description", info.Description},
+		{"Repository", info.Repository},
+		{"Manifest", info.Manifest},
+		{"Version", fmt.Sprintf("%s (%s)", info.Version, info.VersionSource)},
+		{"Platform", platform},
+		{"Source", info.Source},
+		{"Checksum", info.Checksum},
+		{"Bins", strings.Join(bins, ", ")},
+		{"Exec", info.Exec},
+		{"Extract", extract},
+		{"Test", info.Test},
+		{"With-version", strings.Join(info.WithVersions, ", ")},
+		{"With-platform", strings.Join(info.WithPlatforms, ", ")},
+		{"Overrides", strings.Join(info.Overrides, ", ")},
+		{"Path", info.Path},
+		{"Installed", installed},
+	}
+
+	for _, row := range rows {
+		if row[1] != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", row[0], row[1])
+		}
+	}
+
+	return nil
+}
+
+// GetPackageInfo renders the manifest of a package the same way hvm run would. The version
+// defaults to the one set by the current directory's config. Versions asked for explicitly aren't
+// held to the project's hvm.lock.
+func GetPackageInfo(ctx *context.Context, name string, version string) (*PackageInfo, error) {
+	requested := version
+	if requested == "" {
+		requested = ctx.Use[name]
+	} else {
+		unlocked := *ctx
+		unlocked.Lock = nil
+		ctx = &unlocked
+	}
+
+	man, manCtx, err := resolvePackage(ctx, name, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	loader, err := repos.FindPackage(name)
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := manifest.NewPackageManfiestConfig(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PackageInfo{
+		Name:          man.Name,
+		Description:   conf.Description,
+		Repository:    loader.GetName(),
+		Manifest:      filepath.Join(loader.GetPath(), man.Name+".hcl"),
+		Version:       man.Version,
+		VersionSource: versionSource(ctx, name, version),
+		Platform:      manCtx.Platform,
+		Source:        man.Source,
+		Bins:          man.Bins,
+		Exec:          man.Exec,
+		Extract:       man.Extract,
+		Test:          man.Test,
+		Overrides:     ctx.PackageSources[name],
+		Path:          manCtx.OutputDir,
+		Installed:     store.IsInstalled(manCtx.OutputDir),
+	}
+
+	sum, err := man.GetChecksum(manCtx.Platform)
+	if err != nil {
+		return nil, err
+	} else if sum != nil {
+		info.Checksum = sum.String()
+	}
+
+	blocks, err := conf.MatchingVersions(man.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, block := range blocks {
+		info.WithVersions = append(info.WithVersions, block.Version)
+	}
+
+	target := conf.TargetContext(manCtx)
+	if target.Platform != manCtx.Platform {
+		info.Fallback = target.Platform
+	}
+
+	for _, block := range conf.MatchingPlatforms(target.OS, target.Arch) {
+		info.WithPlatforms = append(info.WithPlatforms, block.Platform)
+	}
+
+	return info, nil
+}
+
+// versionSource describes where the version of a package came from
+func versionSource(ctx *context.Context, name string, requested string) string {
+	source := "manifest default"
+
+	switch {
+	case requested != "":
+		source = "requested " + requested
+	case ctx.Use[name] != "":
+		source = fmt.Sprintf("use %s in %s", ctx.Use[name], ctx.UseSource)
+	case ctx.Packages[name] != nil && ctx.Packages[name].Version != "":
+		sources := ctx.PackageSources[name]
+		source = fmt.Sprintf("package block in %s", strings.Join(sources, ", "))
+	}
+
+	if pkg, _ := ctx.Lock.Get(repos.PackageName(name), manifest.Platform()); pkg != nil {
+		source += fmt.Sprintf(", locked in %s", ctx.Lock.GetPath())
+	}
+
+	return source
+}
@@ -0,0 +1,159 @@
+package hvm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/paths"
+	"github.com/josephschmitt/hvm/repos"
+)
+
+const describedManifest = `
+name = "%[1]s"
+description = "%[2]s"
+version = "1.0.0"
+source = "https://example.com/%[1]s-${version}"
+`
+
+func describedRepo(t *testing.T, descriptions map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, description := range descriptions {
+		manifest := fmt.Sprintf(describedManifest, name, description)
+		if err := os.WriteFile(filepath.Join(dir, name+".hcl"), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestSearchPackages(t *testing.T) {
+	usePaths(t)
+	os.Unsetenv(repos.RepoPathEnv)
+
+	repos.Configure([]*repos.Repository{
+		{Name: "team", Type: repos.LocalRepository, Priority: 10, URL: describedRepo(t,
+			map[string]string{"node": "Our patched Node.js"})},
+		{Name: paths.PackageRepository, Type: repos.LocalRepository, URL: describedRepo(t,
+			map[string]string{
+				"node": "JavaScript runtime built on V8",
+				"deno": "A modern runtime for JavaScript and TypeScript",
+				"jq":   "Command-line JSON processor",
+			})},
+	})
+	defer repos.Configure(nil)
+
+	tests := []struct {
+		term     string
+		expected string
+	}{
+		{"node", "node,hvm-packages/node"},
+		{"javascript", "deno,hvm-packages/node"},
+		{"JSON", "jq"},
+		{"patched", "node"},
+		{"python", ""},
+		{"", "node,deno,jq,hvm-packages/node"},
+	}
+
+	for _, test := range tests {
+		pkgs, err := SearchPackages(&context.Context{}, test.term)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, pkg := range pkgs {
+			names = append(names, pkg.Name)
+		}
+
+		if actual := strings.Join(names, ","); actual != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.term, test.expected, actual)
+		}
+	}
+}
+
+func TestGetPackageInfo(t *testing.T) {
+	usePaths(t)
+	dir := useRepo(t, map[string]string{"tool": toolManifest + `
+description = "A tool"
+bins = {
+  "tool": "bin/tool"
+}
+
+with-version ">=2.0.0" {
+  bins = {
+    "tool": "tool"
+  }
+}
+`})
+
+	tests := []struct {
+		name      string
+		use       string
+		block     string
+		lock      string
+		requested string
+		version   string
+		source    string
+		blocks    string
+	}{
+		{"manifest default", "", "", "", "", "1.2.0", "manifest default", ""},
+		{"requested", "1.0.0", "", "", "2.0.0", "2.0.0", "requested 2.0.0", ">=2.0.0"},
+		{"use map", "^1.0.0", "", "", "", "1.2.0", "use ^1.0.0 in config.hcl", ""},
+		{"package block", "", "1.1.0", "", "", "1.1.0", "package block in config.hcl", ""},
+		{"locked", "^1.0.0", "", "1.0.0", "", "1.0.0",
+			"use ^1.0.0 in config.hcl, locked in hvm.lock", ""},
+		{"requested ignores the lock", "^1.0.0", "", "1.0.0", "^1.0.0", "1.2.0",
+			"requested ^1.0.0", ""},
+	}
+
+	for _, test := range tests {
+		ctx := &context.Context{
+			Use:            map[string]string{},
+			Packages:       map[string]*manifest.PackageManifestOptions{},
+			PackageSources: map[string][]string{},
+			UseSource:      "config.hcl",
+		}
+		if test.use != "" {
+			ctx.Use["tool"] = test.use
+		}
+		if test.block != "" {
+			ctx.Packages["tool"] = &manifest.PackageManifestOptions{Version: test.block}
+			ctx.PackageSources["tool"] = []string{"config.hcl"}
+		}
+		if test.lock != "" {
+			ctx.Lock = lockVersion("tool", test.lock)
+		}
+
+		info, err := GetPackageInfo(ctx, "tool", test.requested)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		if info.Version != test.version {
+			t.Errorf("%s: expected version %s, got %s", test.name, test.version, info.Version)
+		}
+		if info.VersionSource != test.source {
+			t.Errorf("%s: expected version source %q, got %q", test.name, test.source,
+				info.VersionSource)
+		}
+		if actual := strings.Join(info.WithVersions, ","); actual != test.blocks {
+			t.Errorf("%s: expected with-version blocks %q, got %q", test.name, test.blocks, actual)
+		}
+		if expected := "https://example.com/tool-" + test.version; info.Source != expected {
+			t.Errorf("%s: expected source %s, got %s", test.name, expected, info.Source)
+		}
+		if info.Description != "A tool" || info.Repository != paths.PackageRepository ||
+			info.Manifest != filepath.Join(dir, "tool.hcl") {
+			t.Errorf("%s: unexpected manifest details %+v", test.name, info)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package hvm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/auth"
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/offline"
+)
+
+// sourceCheckTimeout bounds how long checking that a single source URL exists may take
+const sourceCheckTimeout = 30 * time.Second
+
+// Lint checks package manifests for mistakes. Directories are expanded to the manifests in them,
+// and the current directory is linted if no files are given. With checkSources, every rendered
+// source URL is requested to make sure it exists.
+func Lint(ctx *context.Context, files []string, checkSources bool, asJSON bool) error {
+	if checkSources && offline.Enabled() {
+		return fmt.Errorf("source URLs can't be checked offline")
+	}
+
+	manifests, err := manifestFiles(files)
+	if err != nil {
+		return err
+	}
+
+	if len(manifests) == 0 {
+		return fmt.Errorf("no package manifests to lint")
+	}
+
+	var results []*manifest.LintResult
+	failed := 0
+	problems := 0
+
+	for _, path := range manifests {
+		result := manifest.LintManifest(path)
+		if checkSources {
+			checkSourceURLs(result)
+		}
+
+		if result.HasErrors() {
+			failed++
+		}
+		problems += len(result.Problems)
+		results = append(results, result)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			for _, problem := range result.Problems {
+				severity := colour.Sprintf("^1%s^R", problem.Severity)
+				if problem.Severity == manifest.LintWarning {
+					severity = colour.Sprintf("^3%s^R", problem.Severity)
+				}
+
+				platform := ""
+				if problem.Platform != "" {
+					platform = fmt.Sprintf(" [%s]", problem.Platform)
+				}
+
+				colour.Printf("^6%s^R: %s%s: %s\n", result.Path, severity, platform, problem.Message)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf(colour.Sprintf("^1%d^R of %d manifest(s) have errors", failed,
+			len(manifests)))
+	}
+
+	if !asJSON {
+		colour.Printf("^2%d^R manifest(s) OK, %d warning(s)\n", len(manifests), problems)
+	}
+
+	return nil
+}
+
+// manifestFiles expands directories to the manifests in them, defaulting to the working directory
+func manifestFiles(files []string) ([]string, error) {
+	if len(files) == 0 {
+		files = []string{"."}
+	}
+
+	var manifests []string
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			manifests = append(manifests, file)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(file, "*.hcl"))
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, matches...)
+	}
+
+	return manifests, nil
+}
+
+// checkSourceURLs requests each distinct source URL a manifest renders to, flagging any that don't
+// exist
+func checkSourceURLs(result *manifest.LintResult) {
+	platforms := make(map[string][]string)
+	for platform, source := range result.Sources {
+		platforms[source] = append(platforms[source], platform)
+	}
+
+	var sources []string
+	for source := range platforms {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	client := &http.Client{Timeout: sourceCheckTimeout}
+
+	for _, source := range sources {
+		if err := checkURL(client, source); err != nil {
+			sort.Strings(platforms[source])
+
+			result.Problems = append(result.Problems, &manifest.LintProblem{
+				Severity: manifest.LintError,
+				Platform: strings.Join(platforms[source], ","),
+				Message:  colour.Sprintf("source ^1%s^R: %s", source, err),
+			})
+		}
+	}
+}
+
+// checkURL makes a HEAD request to url, falling back to GET for servers that don't support HEAD
+func checkURL(client *http.Client, url string) error {
+	resp, err := authorizedRequest(client, http.MethodHead, url)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed ||
+		resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = authorizedRequest(client, http.MethodGet, url)
+	}
+
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	return nil
+}
+
+func authorizedRequest(client *http.Client, method string, url string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := auth.Authorize(req); err != nil {
+		return nil, err
+	}
+
+	return client.Do(req)
+}
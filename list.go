@@ -0,0 +1,258 @@
+package hvm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/repos"
+	"github.com/josephschmitt/hvm/store"
+	"github.com/josephschmitt/hvm/tmpl"
+)
+
+const (
+	ListInstalled = "installed"
+	ListLinked    = "linked"
+	ListAvailable = "available"
+)
+
+type InstalledPackage struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	Path      string    `json:"path"`
+	Installed time.Time `json:"installed,omitempty"`
+	Selected  bool      `json:"selected"`
+}
+
+type LinkedBin struct {
+	Bin     string `json:"bin"`
+	Package string `json:"package"`
+	Path    string `json:"path"`
+}
+
+type AvailablePackage struct {
+	Name        string `json:"name"`
+	Repository  string `json:"repository"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	Selected    bool   `json:"selected"`
+}
+
+// List prints the installed packages, linked bins or available packages, depending on mode
+func List(ctx *context.Context, mode string, asJSON bool) error {
+	var rows interface{}
+	var err error
+
+	switch mode {
+	case ListInstalled, "":
+		rows, err = ListInstalledPackages(ctx)
+	case ListLinked:
+		rows, err = ListLinkedBins(ctx)
+	case ListAvailable:
+		rows, err = ListAvailablePackages(ctx)
+	default:
+		return fmt.Errorf("unknown list mode \"%s\"", mode)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return printList(rows, asJSON)
+}
+
+// printList prints rows of one of the list types as a table, or as JSON
+func printList(rows interface{}, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	switch rows := rows.(type) {
+	case []*InstalledPackage:
+		fmt.Fprintln(w, "PACKAGE\tVERSION\tPATH")
+		for _, pkg := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", pkg.Name, selectedMarker(pkg.Version, pkg.Selected),
+				pkg.Path)
+		}
+	case []*LinkedBin:
+		fmt.Fprintln(w, "BIN\tPACKAGE\tPATH")
+		for _, link := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", link.Bin, link.Package, link.Path)
+		}
+	case []*AvailablePackage:
+		fmt.Fprintln(w, "PACKAGE\tVERSION\tREPOSITORY\tDESCRIPTION")
+		for _, pkg := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", pkg.Name, selectedMarker(pkg.Version, pkg.Selected),
+				pkg.Repository, pkg.Description)
+		}
+	}
+
+	return nil
+}
+
+// ListInstalledPackages walks PkgsDirectory for every completed install
+func ListInstalledPackages(ctx *context.Context) ([]*InstalledPackage, error) {
+	pkgs := []*InstalledPackage{}
+
+	names, err := store.InstalledPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		versions, err := store.InstalledVersions(name)
+		if err != nil {
+			return nil, err
+		}
+
+		selected := selectedVersion(ctx, name)
+
+		for _, version := range versions {
+			pkg := &InstalledPackage{
+				Name:     name,
+				Version:  version,
+				Path:     store.PackageDir(name, version),
+				Selected: version == selected,
+			}
+
+			if receipt, err := store.ReadReceipt(pkg.Path); err == nil {
+				pkg.Installed = receipt.Installed
+			}
+
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	return pkgs, nil
+}
+
+// ListLinkedBins finds every hvm run script in the link directory
+func ListLinkedBins(ctx *context.Context) ([]*LinkedBin, error) {
+	links := []*LinkedBin{}
+
+	files, err := os.ReadDir(ctx.LinkDir)
+	if os.IsNotExist(err) {
+		return links, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(ctx.LinkDir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil || !isHVMScript(bytes.NewReader(data)) {
+			continue
+		}
+
+		name, _, _ := tmpl.ParseRunScript(string(data))
+		links = append(links, &LinkedBin{
+			Bin:     file.Name(),
+			Package: name,
+			Path:    path,
+		})
+	}
+
+	return links, nil
+}
+
+// ListAvailablePackages lists every package manifest in the package repositories. Packages shadowed
+// by one of the same name in a higher priority repository are listed as "repo/pkg".
+func ListAvailablePackages(ctx *context.Context) ([]*AvailablePackage, error) {
+	pkgs := []*AvailablePackage{}
+	seen := make(map[string]bool)
+
+	repos.GetMissing()
+
+	for _, loader := range repos.Loaders() {
+		names, err := loader.ListPackages()
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			if seen[name] {
+				name = loader.GetName() + "/" + name
+			}
+			seen[name] = true
+
+			pkgs = append(pkgs, availablePackage(ctx, name, loader.GetName()))
+		}
+	}
+
+	return pkgs, nil
+}
+
+func availablePackage(ctx *context.Context, name string, repository string) *AvailablePackage {
+	pkg := &AvailablePackage{Name: name, Repository: repository}
+
+	if conf, err := manifest.NewPackageManfiestConfig(name); err == nil {
+		pkg.Version = conf.Version
+		pkg.Description = conf.Description
+	}
+
+	if version := configuredVersion(ctx, name); version != "" {
+		pkg.Version = selectedVersion(ctx, name)
+		pkg.Selected = true
+	}
+
+	return pkg
+}
+
+// configuredVersion returns the version of a package set by the current directory's config
+func configuredVersion(ctx *context.Context, name string) string {
+	if version := ctx.Use[name]; version != "" {
+		return version
+	}
+
+	if pkg := ctx.Packages[name]; pkg != nil {
+		return pkg.Version
+	}
+
+	return ""
+}
+
+// selectedVersion returns the version of a package the current directory's config would run,
+// resolved the same way as resolvePackage: through the package's config block, version ranges and
+// hvm.lock, falling back to the default version declared in its manifest
+func selectedVersion(ctx *context.Context, name string) string {
+	version, _, err := packageVersion(ctx, name, ctx.Use[name])
+	if err != nil {
+		return configuredVersion(ctx, name)
+	} else if version != "" {
+		return version
+	}
+
+	if conf, err := manifest.NewPackageManfiestConfig(name); err == nil {
+		return conf.Version
+	}
+
+	return ""
+}
+
+// selectedMarker flags the selected version in table output. Colours are left out since their
+// escape codes would throw off the column alignment.
+func selectedMarker(version string, selected bool) string {
+	if selected {
+		return version + " *"
+	}
+
+	return version
+}
@@ -0,0 +1,105 @@
+package hvm
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/lockfile"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/store"
+)
+
+const toolManifest = `
+name = "tool"
+version = "1.2.0"
+versions = ["1.0.0", "1.1.0", "1.2.0", "2.0.0"]
+source = "https://example.com/tool-${version}"
+`
+
+// installVersions fakes completed installs of a package
+func installVersions(t *testing.T, name string, versions ...string) {
+	t.Helper()
+
+	for _, version := range versions {
+		dir := store.PackageDir(name, version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		receipt := &store.Receipt{Name: name, Version: version, Installed: time.Now()}
+		if err := store.WriteReceipt(dir, receipt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func lockVersion(name string, version string) *lockfile.Lockfile {
+	lock := lockfile.New("hvm.lock")
+	lock.Packages[name] = &lockfile.Package{
+		Version: version,
+		Platforms: map[string]*lockfile.Platform{
+			manifest.PlatformFor(runtime.GOOS, runtime.GOARCH): {
+				Source: "https://example.com/tool-" + version,
+			},
+		},
+	}
+
+	return lock
+}
+
+func TestListInstalledPackagesSelected(t *testing.T) {
+	tests := []struct {
+		name     string
+		use      string
+		block    string
+		lock     *lockfile.Lockfile
+		expected string
+	}{
+		{"manifest default", "", "", nil, "1.2.0"},
+		{"exact version", "1.0.0", "", nil, "1.0.0"},
+		{"package block version", "", "1.1.0", nil, "1.1.0"},
+		{"use map over package block", "1.0.0", "1.1.0", nil, "1.0.0"},
+		{"version range", "^1.0.0", "", nil, "1.2.0"},
+		{"version range held by hvm.lock", "^1.0.0", "", lockVersion("tool", "1.1.0"), "1.1.0"},
+		{"hvm.lock outside the range", "~1.2.0", "", lockVersion("tool", "1.1.0"), "1.2.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usePaths(t)
+			useRepo(t, map[string]string{"tool": toolManifest})
+			installVersions(t, "tool", "1.0.0", "1.1.0", "1.2.0")
+
+			ctx := &context.Context{
+				Use:      map[string]string{},
+				Packages: map[string]*manifest.PackageManifestOptions{},
+				Lock:     test.lock,
+			}
+			if test.use != "" {
+				ctx.Use["tool"] = test.use
+			}
+			if test.block != "" {
+				ctx.Packages["tool"] = &manifest.PackageManifestOptions{Version: test.block}
+			}
+
+			pkgs, err := ListInstalledPackages(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var selected []string
+			for _, pkg := range pkgs {
+				if pkg.Selected {
+					selected = append(selected, pkg.Version)
+				}
+			}
+
+			if len(selected) != 1 || selected[0] != test.expected {
+				t.Errorf("selected %q, expected %s", selected, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package hvm
+
+import (
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/lockfile"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/repos"
+	"github.com/josephschmitt/hvm/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// Lock (re)generates the project's hvm.lock, recording the version, rendered source, bins and
+// checksum of every configured package on every supported platform
+func Lock(ctx *context.Context) error {
+	names := ctx.ConfiguredPackages()
+	if len(names) == 0 {
+		log.Warnf("No packages found in config.hcl to lock\n")
+		return nil
+	}
+
+	lock := lockfile.New(lockfile.Path())
+
+	// Resolve versions afresh, rather than to what's already locked
+	unlocked := *ctx
+	unlocked.Lock = nil
+	ctx = &unlocked
+
+	for _, name := range names {
+		version, overrides, err := packageVersion(ctx, name, ctx.Use[name])
+		if err != nil {
+			return err
+		}
+
+		hostCtx := manifest.NewManifestContext(name, version)
+
+		man, err := manifest.NewPackageManfiest(name, hostCtx, overrides)
+		if err != nil {
+			return err
+		}
+
+		var revision string
+		if loader, err := repos.FindPackage(name); err == nil {
+			if revision, err = loader.GetRevision(); err != nil {
+				log.Warnf("Unable to determine package repository revision: %s", err)
+			}
+		}
+
+		pkg := &lockfile.Package{
+			Version:    man.Version,
+			Repository: revision,
+			Platforms:  make(map[string]*lockfile.Platform),
+		}
+
+		for _, platform := range manifest.SupportedPlatforms {
+			manCtx := manifest.NewPlatformManifestContext(name, man.Version, platform.OS,
+				platform.Arch)
+
+			platMan, err := manifest.NewPackageManfiest(name, manCtx, overrides)
+			if err != nil {
+				return err
+			}
+
+			plat := &lockfile.Platform{
+				Source: platMan.Source,
+				Bins:   platMan.Bins,
+			}
+
+			sum, err := platMan.GetChecksum(manCtx.Platform)
+			if err != nil {
+				return err
+			}
+
+			if sum != nil {
+				plat.Checksum = sum.String()
+			} else if manCtx.Platform == hostCtx.Platform {
+				// Without a declared checksum, pin whatever this platform downloads
+				if err := InstallPackage(ctx, man, hostCtx); err != nil {
+					return err
+				}
+
+				receipt, err := store.ReadReceipt(hostCtx.OutputDir)
+				if err != nil {
+					return err
+				}
+
+				if receipt.Source == plat.Source {
+					plat.Checksum = receipt.Checksum
+				} else {
+					log.Warnf(colour.Sprintf("^3%s@%s^R was installed from ^6%s^R, not locking its "+
+						"checksum", name, man.Version, receipt.Source))
+				}
+			}
+
+			pkg.Platforms[manCtx.Platform] = plat
+		}
+
+		lock.Packages[man.Name] = pkg
+		colour.Printf("Locked ^3%s@%s^R\n", man.Name, man.Version)
+	}
+
+	if err := lock.Save(); err != nil {
+		return err
+	}
+
+	colour.Printf("Wrote ^6%s^R\n", lock.GetPath())
+	return nil
+}
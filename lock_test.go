@@ -0,0 +1,79 @@
+package hvm
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/josephschmitt/hvm/lockfile"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/store"
+)
+
+func TestApplyLock(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	other := strings.Repeat("cd", 32)
+
+	tests := []struct {
+		name      string
+		version   string
+		locked    string
+		checksum  string
+		installed string
+		expected  string
+		ok        bool
+	}{
+		{"no lockfile", "1.0.0", "", "", "", "", true},
+		{"locked without checksum", "1.0.0", "1.0.0", "", "", "", true},
+		{"locked checksum", "1.0.0", "1.0.0", "sha256:" + digest, "", digest, true},
+		{"matching install", "1.0.0", "1.0.0", "sha256:" + digest, "sha256:" + digest, digest, true},
+		{"mismatched install", "1.0.0", "1.0.0", "sha256:" + digest, "sha256:" + other, "", false},
+		{"different version", "1.1.0", "1.0.0", "sha256:" + digest, "", "", false},
+		{"invalid checksum", "1.0.0", "1.0.0", "sha256:nope", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usePaths(t)
+
+			manCtx := manifest.NewPlatformManifestContext("tool", test.version, runtime.GOOS,
+				runtime.GOARCH)
+			man := &manifest.PackageManifest{Name: "tool"}
+			man.Version = test.version
+			man.Source = "https://example.com/tool-" + test.version
+			man.Sha512 = map[string]string{"*": strings.Repeat("ef", 64)}
+
+			var lock *lockfile.Lockfile
+			if test.locked != "" {
+				lock = lockVersion("tool", test.locked)
+				lock.Packages["tool"].Platforms[manCtx.Platform].Checksum = test.checksum
+			}
+
+			if test.installed != "" {
+				installVersions(t, "tool", test.version)
+				receipt := &store.Receipt{Name: "tool", Version: test.version,
+					Checksum: test.installed}
+				if err := store.WriteReceipt(manCtx.OutputDir, receipt); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := applyLock(lock, man, manCtx)
+			if test.ok != (err == nil) {
+				t.Fatalf("expected ok=%t, got error %v", test.ok, err)
+			}
+			if !test.ok || test.expected == "" {
+				return
+			}
+
+			sum, err := man.GetChecksum(manCtx.Platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum.String() != "sha256:"+test.expected {
+				t.Errorf("expected the download to be pinned to sha256:%s, got %s", test.expected,
+					sum)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/paths"
+)
+
+const FileName = "hvm.lock"
+
+// Lockfile pins the exact bits each package in a project resolves to, so that every developer
+// running the same config gets the same download regardless of the package repository's state
+type Lockfile struct {
+	Packages map[string]*Package `json:"packages"`
+
+	path string
+}
+
+// Package is the resolved state of a single package in the lockfile
+type Package struct {
+	Version    string               `json:"version"`
+	Repository string               `json:"repository,omitempty"`
+	Platforms  map[string]*Platform `json:"platforms"`
+}
+
+// Platform is the rendered manifest of a package for a single platform
+type Platform struct {
+	Source   string            `json:"source"`
+	Bins     map[string]string `json:"bins,omitempty"`
+	Checksum string            `json:"checksum,omitempty"`
+}
+
+// Path returns the location of the lockfile for the current project, next to its config.hcl
+func Path() string {
+	return filepath.Join(paths.AppPaths.ProjectConfigDir(), FileName)
+}
+
+func New(lockPath string) *Lockfile {
+	return &Lockfile{
+		Packages: make(map[string]*Package),
+		path:     lockPath,
+	}
+}
+
+// Load reads the lockfile at lockPath, returning nil if there isn't one
+func Load(lockPath string) (*Lockfile, error) {
+	data, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	lock := New(lockPath)
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf(colour.Sprintf("unable to parse ^6%s^R: %s", lockPath, err))
+	}
+
+	return lock, nil
+}
+
+func (l *Lockfile) Save() error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(l.path, append(data, '\n'), 0644)
+}
+
+func (l *Lockfile) GetPath() string {
+	return l.path
+}
+
+// Get returns the locked state of a package on a platform, or nil if it isn't in the lockfile
+func (l *Lockfile) Get(name string, platform string) (*Package, *Platform) {
+	if l == nil {
+		return nil, nil
+	}
+
+	pkg := l.Packages[name]
+	if pkg == nil {
+		return nil, nil
+	}
+
+	return pkg, pkg.Platforms[platform]
+}
+
+// Check returns an error if the resolved version, source or bins of a package differ from what the
+// lockfile pinned for the platform
+func (l *Lockfile) Check(
+	name string,
+	platform string,
+	version string,
+	source string,
+	bins map[string]string,
+) error {
+	pkg, plat := l.Get(name, platform)
+	if pkg == nil {
+		return nil
+	}
+
+	hint := colour.Sprintf("Run ^5hvm lock^R to update ^6%s^R.", l.path)
+
+	if pkg.Version != version {
+		return fmt.Errorf(colour.Sprintf("^3%s^R is locked to version ^2%s^R, but config selects "+
+			"^1%s^R. ", name, pkg.Version, version) + hint)
+	}
+
+	if plat == nil {
+		return fmt.Errorf(colour.Sprintf("^3%s@%s^R is not locked for platform ^5%s^R. ", name,
+			version, platform) + hint)
+	}
+
+	if plat.Source != source {
+		return fmt.Errorf(colour.Sprintf("^3%s@%s^R is locked to source ^2%s^R, but the manifest "+
+			"now renders ^1%s^R. ", name, version, plat.Source, source) + hint)
+	}
+
+	if len(plat.Bins) > 0 && !reflect.DeepEqual(plat.Bins, bins) {
+		return fmt.Errorf(colour.Sprintf("^3%s@%s^R is locked to bins ^2%v^R, but the manifest "+
+			"now declares ^1%v^R. ", name, version, plat.Bins, bins) + hint)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package lockfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testLockfile(lockPath string) *Lockfile {
+	lock := New(lockPath)
+	lock.Packages["node"] = &Package{
+		Version:    "18.1.0",
+		Repository: "hvm-packages",
+		Platforms: map[string]*Platform{
+			"linux-x64": {
+				Source:   "https://nodejs.org/dist/v18.1.0/node-v18.1.0-linux-x64.tar.gz",
+				Bins:     map[string]string{"node": "bin/node", "npm": "bin/npm"},
+				Checksum: "sha256:ab12",
+			},
+			"darwin-arm64": {
+				Source: "https://nodejs.org/dist/v18.1.0/node-v18.1.0-darwin-arm64.tar.gz",
+			},
+		},
+	}
+
+	return lock
+}
+
+func TestSaveLoad(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), ".hvm", FileName)
+	lock := testLockfile(lockPath)
+
+	if err := lock.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.GetPath() != lockPath {
+		t.Errorf("loaded lockfile has path %s, expected %s", loaded.GetPath(), lockPath)
+	}
+	if !reflect.DeepEqual(loaded.Packages, lock.Packages) {
+		t.Errorf("loaded %+v, expected %+v", loaded.Packages, lock.Packages)
+	}
+
+	// Saving again doesn't change anything, so the lockfile doesn't churn in version control
+	before, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Errorf("saving a loaded lockfile changed it from\n%s\nto\n%s", before, after)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	lock, err := Load(filepath.Join(dir, FileName))
+	if err != nil || lock != nil {
+		t.Errorf("expected no lockfile and no error, got %v, %v", lock, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.lock")
+	if err := os.WriteFile(invalid, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(invalid); err == nil {
+		t.Error("expected an invalid lockfile to fail")
+	}
+}
+
+func TestGet(t *testing.T) {
+	var missing *Lockfile
+	if pkg, plat := missing.Get("node", "linux-x64"); pkg != nil || plat != nil {
+		t.Error("expected nothing from a missing lockfile")
+	}
+
+	lock := testLockfile(FileName)
+
+	tests := []struct {
+		name     string
+		platform string
+		pkg      bool
+		plat     bool
+	}{
+		{"node", "linux-x64", true, true},
+		{"node", "windows-x64", true, false},
+		{"deno", "linux-x64", false, false},
+	}
+
+	for _, test := range tests {
+		pkg, plat := lock.Get(test.name, test.platform)
+		if (pkg != nil) != test.pkg || (plat != nil) != test.plat {
+			t.Errorf("Get(%s, %s) = %v, %v", test.name, test.platform, pkg, plat)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	lock := testLockfile(FileName)
+	source := lock.Packages["node"].Platforms["linux-x64"].Source
+	bins := map[string]string{"node": "bin/node", "npm": "bin/npm"}
+
+	tests := []struct {
+		name     string
+		pkg      string
+		platform string
+		version  string
+		source   string
+		bins     map[string]string
+		ok       bool
+	}{
+		{"matches", "node", "linux-x64", "18.1.0", source, bins, true},
+		{"not locked", "deno", "linux-x64", "1.0.0", "https://example.com", nil, true},
+		{"different version", "node", "linux-x64", "18.2.0", source, bins, false},
+		{"platform not locked", "node", "windows-x64", "18.1.0", source, bins, false},
+		{"different source", "node", "linux-x64", "18.1.0", source + "?mirror", bins, false},
+		{"different bins", "node", "linux-x64", "18.1.0", source, map[string]string{"node": "node"},
+			false},
+		{"bins not locked", "node", "darwin-arm64", "18.1.0",
+			lock.Packages["node"].Platforms["darwin-arm64"].Source, bins, true},
+	}
+
+	for _, test := range tests {
+		err := lock.Check(test.pkg, test.platform, test.version, test.source, test.bins)
+		if test.ok != (err == nil) {
+			t.Errorf("%s: expected ok=%t, got error %v", test.name, test.ok, err)
+		}
+	}
+
+	var missing *Lockfile
+	if err := missing.Check("node", "linux-x64", "1.0.0", source, bins); err != nil {
+		t.Errorf("expected a missing lockfile to allow anything, got %v", err)
+	}
+}
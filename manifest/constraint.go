@@ -0,0 +1,145 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/colour"
+	"github.com/blang/semver/v4"
+)
+
+// IsExactVersion reports whether version is a single semver version rather than a range, with or
+// without a leading "v"
+func IsExactVersion(version string) bool {
+	_, err := semver.Parse(strings.TrimPrefix(version, "v"))
+	return err == nil
+}
+
+// ParseConstraint parses a version range. On top of the comparators understood by semver.ParseRange,
+// it accepts npm-style caret (^1.2) and tilde (~1.2) ranges, partial versions (>=1.2 <2) and a
+// leading "v" on versions.
+func ParseConstraint(constraint string) (semver.Range, error) {
+	var ors []string
+	for _, or := range strings.Split(constraint, "||") {
+		var ands []string
+
+		fields := strings.Fields(or)
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+
+			// Allow a space between the operator and the version, e.g. ">= 1.2"
+			if strings.Trim(field, "<>=!^~") == "" && i+1 < len(fields) {
+				i++
+				field += fields[i]
+			}
+
+			expanded, err := expandComparator(field)
+			if err != nil {
+				return nil, err
+			}
+			ands = append(ands, expanded...)
+		}
+
+		ors = append(ors, strings.Join(ands, " "))
+	}
+
+	expectedRange, err := semver.ParseRange(strings.Join(ors, " || "))
+	if err != nil {
+		return nil, fmt.Errorf(colour.Sprintf("invalid version range ^1%s^R: %s", constraint, err))
+	}
+
+	return expectedRange, nil
+}
+
+// expandComparator rewrites a single comparator into ones semver.ParseRange understands
+func expandComparator(comparator string) ([]string, error) {
+	op := comparator[:len(comparator)-len(strings.TrimLeft(comparator, "<>=!^~"))]
+	version := strings.TrimPrefix(comparator[len(op):], "v")
+
+	if isWildcard(version) {
+		return []string{">=0.0.0"}, nil
+	}
+
+	parts := strings.Split(version, ".")
+	for len(parts) < 3 {
+		parts = append(parts, "x")
+	}
+
+	// A bare partial version like "1.2" matches any patch of it, same as "~1.2"
+	if (op == "" || op == "=") && isWildcard(parts[2]) {
+		op = "~"
+	}
+
+	switch op {
+	case "^", "~":
+		nums := make([]uint64, 3)
+		for i, part := range parts[:3] {
+			if part == "x" || part == "X" || part == "*" {
+				break
+			}
+
+			n, err := strconv.ParseUint(part, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf(colour.Sprintf("invalid version ^1%s^R in range", comparator))
+			}
+			nums[i] = n
+		}
+
+		lower := fmt.Sprintf(">=%d.%d.%d", nums[0], nums[1], nums[2])
+		var upper string
+		switch {
+		case op == "~" && !isWildcard(parts[1]):
+			upper = fmt.Sprintf("<%d.%d.0", nums[0], nums[1]+1)
+		case op == "^" && nums[0] == 0 && !isWildcard(parts[1]):
+			upper = fmt.Sprintf("<0.%d.0", nums[1]+1)
+		default:
+			upper = fmt.Sprintf("<%d.0.0", nums[0]+1)
+		}
+
+		return []string{lower, upper}, nil
+	}
+
+	return []string{op + strings.Join(parts, ".")}, nil
+}
+
+func isWildcard(part string) bool {
+	return part == "x" || part == "X" || part == "*"
+}
+
+// ResolveVersion picks the highest of versions that satisfies constraint. Pre-release versions are
+// skipped unless the constraint is an exact version. The version is returned as it's spelled in
+// versions (e.g. with a leading "v"), since that's what manifests key their version blocks by.
+func ResolveVersion(constraint string, versions []string) (string, error) {
+	if IsExactVersion(constraint) {
+		return constraint, nil
+	}
+
+	expectedRange, err := ParseConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+
+	var matches semver.Versions
+	spellings := make(map[string]string)
+	for _, version := range versions {
+		v, err := semver.ParseTolerant(version)
+		if err != nil || len(v.Pre) > 0 || !expectedRange(v) {
+			continue
+		}
+
+		// The first spelling of a version wins, e.g. the manifest's over an installed directory's
+		if _, ok := spellings[v.String()]; !ok {
+			spellings[v.String()] = version
+			matches = append(matches, v)
+		}
+	}
+
+	if len(matches) == 0 {
+		return "", fmt.Errorf(colour.Sprintf("no known version satisfies ^1%s^R", constraint))
+	}
+
+	sort.Sort(matches)
+	return spellings[matches[len(matches)-1].String()], nil
+}
@@ -0,0 +1,106 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestIsExactVersion(t *testing.T) {
+	tests := map[string]bool{
+		"1.2.3":        true,
+		"v1.2.3":       true,
+		"1.2.3-beta.1": true,
+		"1.2":          false,
+		"^1.2.3":       false,
+		">=1.2.3":      false,
+		"*":            false,
+		"":             false,
+	}
+
+	for version, expected := range tests {
+		if actual := IsExactVersion(version); actual != expected {
+			t.Errorf("IsExactVersion(%q) = %t, expected %t", version, actual, expected)
+		}
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matches    []string
+		misses     []string
+	}{
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0"}},
+		{"^1.2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "1.0.0"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{"1.x", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"*", []string{"0.0.1", "9.9.9"}, nil},
+		{">=1.2 <2", []string{"1.2.0", "1.9.9"}, []string{"1.1.0", "2.0.0"}},
+		{">= 1.2.0", []string{"1.2.0", "3.0.0"}, []string{"1.1.9"}},
+		{"^v1.2.0", []string{"1.2.0"}, []string{"2.0.0"}},
+		{"^1.0.0 || ^3.0.0", []string{"1.5.0", "3.1.0"}, []string{"2.0.0"}},
+	}
+
+	for _, test := range tests {
+		expectedRange, err := ParseConstraint(test.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) failed: %s", test.constraint, err)
+			continue
+		}
+
+		for _, version := range test.matches {
+			if !expectedRange(semver.MustParse(version)) {
+				t.Errorf("expected %q to match %s", test.constraint, version)
+			}
+		}
+		for _, version := range test.misses {
+			if expectedRange(semver.MustParse(version)) {
+				t.Errorf("expected %q not to match %s", test.constraint, version)
+			}
+		}
+	}
+
+	for _, constraint := range []string{"^a.b", ">=nope", "<=1.2.3 ||"} {
+		if _, err := ParseConstraint(constraint); err == nil {
+			t.Errorf("expected ParseConstraint(%q) to fail", constraint)
+		}
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	versions := []string{"1.0.0", "1.2.0", "1.3.0-beta.1", "2.0.0", "not-a-version"}
+
+	tests := []struct {
+		constraint string
+		versions   []string
+		expected   string
+		ok         bool
+	}{
+		{"^1.0.0", versions, "1.2.0", true},
+		{"*", versions, "2.0.0", true},
+		{"1.3.0-beta.1", versions, "1.3.0-beta.1", true},
+		{"3.0.0", versions, "3.0.0", true},
+		{"^3.0.0", versions, "", false},
+		{"^1.0.0", nil, "", false},
+		{"^1.0.0", []string{"v1.0.0", "v1.2.0"}, "v1.2.0", true},
+		{"v1.0.0", []string{"1.0.0"}, "v1.0.0", true},
+		{"^1.0.0", []string{"v1.2.0", "1.2.0"}, "v1.2.0", true},
+		{"^1.0.0", []string{"1.2.0", "v1.2.0"}, "1.2.0", true},
+	}
+
+	for _, test := range tests {
+		version, err := ResolveVersion(test.constraint, test.versions)
+		if test.ok != (err == nil) {
+			t.Errorf("ResolveVersion(%q, %q): expected ok=%t, got error %v", test.constraint,
+				test.versions, test.ok, err)
+		}
+		if version != test.expected {
+			t.Errorf("ResolveVersion(%q, %q) = %q, expected %q", test.constraint, test.versions,
+				version, test.expected)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package manifest
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/colour"
+	"github.com/alecthomas/hcl"
+	"github.com/blang/semver/v4"
+	"github.com/josephschmitt/hvm/checksum"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintProblem is something wrong with a manifest. Platform is set for problems that only occur
+// when the manifest is rendered for that platform.
+type LintProblem struct {
+	Severity string `json:"severity"`
+	Platform string `json:"platform,omitempty"`
+	Message  string `json:"message"`
+}
+
+// LintResult holds the problems found in a manifest, and the source URL it renders to on every
+// supported platform
+type LintResult struct {
+	Path     string            `json:"path"`
+	Problems []*LintProblem    `json:"problems"`
+	Sources  map[string]string `json:"sources,omitempty"`
+}
+
+func (result *LintResult) add(severity string, platform string, format string, a ...interface{}) {
+	result.Problems = append(result.Problems, &LintProblem{
+		Severity: severity,
+		Platform: platform,
+		Message:  colour.Sprintf(format, a...),
+	})
+}
+
+// HasErrors reports whether any of the problems are errors rather than warnings
+func (result *LintResult) HasErrors() bool {
+	for _, problem := range result.Problems {
+		if problem.Severity == LintError {
+			return true
+		}
+	}
+
+	return false
+}
+
+var placeholderPattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// LintManifest checks a package manifest file for mistakes that would otherwise only surface when
+// someone runs the package, and renders it for every supported platform
+func LintManifest(file string) *LintResult {
+	result := &LintResult{Path: file, Problems: []*LintProblem{}, Sources: map[string]string{}}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		result.add(LintError, "", "%s", err)
+		return result
+	}
+
+	conf := &PackageManifestConfig{}
+	if err := hcl.Unmarshal(data, conf); err != nil {
+		result.add(LintError, "", "unable to parse manifest: %s", err)
+		return result
+	}
+
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if conf.Name != name {
+		result.add(LintError, "", "name ^1%s^R doesn't match the file name ^3%s^R, so the package "+
+			"can't be found", conf.Name, name)
+	}
+
+	if conf.Version == "" {
+		result.add(LintError, "", "no default version")
+	} else if !IsExactVersion(conf.Version) {
+		result.add(LintError, "", "default version ^1%s^R isn't a semver version", conf.Version)
+	}
+
+	for _, version := range conf.AvailableVersions {
+		if _, err := semver.ParseTolerant(version); err != nil {
+			result.add(LintError, "", "version ^1%s^R in versions isn't a semver version", version)
+		}
+	}
+
+	if conf.VersionsURL != "" {
+		lintURL(result, "", "versions-url", conf.VersionsURL)
+	}
+
+	lintOptions(result, "", "", &conf.PackageManifestOptions)
+
+	for _, block := range conf.Versions {
+		if _, err := ParseConstraint(block.Version); err != nil {
+			result.add(LintError, "", "with-version block has %s", err)
+		}
+		lintOptions(result, "", "", &block.PackageManifestOptions)
+	}
+
+	for _, block := range conf.Platforms {
+		if _, err := path.Match(block.Platform, ""); err != nil {
+			result.add(LintError, "", "with-platform pattern ^1%s^R is invalid: %s", block.Platform,
+				err)
+		} else if !matchesSupportedPlatform(conf, block) {
+			result.add(LintWarning, "", "with-platform ^1%s^R doesn't match any supported platform",
+				block.Platform)
+		}
+		lintOptions(result, "", "", &block.PackageManifestOptions)
+	}
+
+	for platform, fallback := range conf.PlatformFallbacks {
+		for _, name := range []string{platform, fallback} {
+			if _, _, err := ParsePlatform(name); err != nil {
+				result.add(LintError, "", "platform-fallbacks: %s", err)
+			}
+		}
+	}
+
+	known := (&PackageManifestContext{}).Variables()
+	for name := range declaredVars(conf) {
+		known[varPrefix+name] = ""
+	}
+
+	for _, match := range placeholderPattern.FindAllStringSubmatch(string(data), -1) {
+		name, _, err := parseTag(match[1])
+		if err != nil {
+			result.add(LintError, "", "%s", err)
+		} else if _, ok := known[name]; !ok {
+			result.add(LintError, "", "unknown template variable ^1%s^R, expected one of %s",
+				match[0], strings.Join(variableNames(known), ", "))
+		}
+	}
+
+	// Problems in the default version would otherwise be repeated for every platform
+	if result.HasErrors() {
+		return result
+	}
+
+	for _, platform := range SupportedPlatforms {
+		lintPlatform(result, data, conf.Name, conf.Version, platform.OS, platform.Arch)
+	}
+
+	return result
+}
+
+// lintPlatform renders the manifest for a platform and checks the result
+func lintPlatform(
+	result *LintResult,
+	data []byte,
+	name string,
+	version string,
+	goos string,
+	goarch string,
+) {
+	ctx := NewPlatformManifestContext(name, version, goos, goarch)
+
+	if arch[goarch] == "" || xarch[goarch] == "" {
+		result.add(LintError, ctx.Platform, "architecture ^1%s^R has no ${platform} mapping", goarch)
+	}
+
+	conf := &PackageManifestConfig{}
+	if err := hcl.Unmarshal(data, conf); err != nil {
+		result.add(LintError, ctx.Platform, "unable to parse manifest: %s", err)
+		return
+	}
+
+	if err := conf.Merge(nil, ctx); err != nil {
+		result.add(LintError, ctx.Platform, "unable to merge with-version blocks: %s", err)
+		return
+	}
+
+	if err := conf.Render(ctx); err != nil {
+		result.add(LintError, ctx.Platform, "unable to render manifest: %s", err)
+		return
+	}
+
+	if conf.Source == "" {
+		result.add(LintError, ctx.Platform, "no source")
+	} else {
+		lintURL(result, ctx.Platform, "source", conf.Source)
+		result.Sources[ctx.Platform] = conf.Source
+	}
+
+	lintOptions(result, ctx.Platform, ctx.OutputDir, &conf.PackageManifestOptions)
+}
+
+// lintOptions checks the options shared by a manifest and its with-version blocks. Once rendered
+// for a platform, bins may be absolute paths within outputDir.
+func lintOptions(
+	result *LintResult,
+	platform string,
+	outputDir string,
+	opts *PackageManifestOptions,
+) {
+	for bin, binPath := range opts.Bins {
+		if bin == "" || bin == "." || bin == ".." || strings.ContainsAny(bin, `/\`) {
+			result.add(LintError, platform, "bin name ^1%s^R isn't a valid file name", bin)
+		}
+
+		// Placeholders are checked once rendered, since ${output} makes a path absolute
+		if platform == "" && strings.Contains(binPath, "${") {
+			continue
+		}
+
+		relPath := binPath
+		if outputDir != "" && filepath.IsAbs(binPath) {
+			if rel, err := filepath.Rel(outputDir, binPath); err == nil {
+				relPath = rel
+			}
+		}
+
+		cleaned := filepath.Clean(relPath)
+		switch {
+		case binPath == "":
+			result.add(LintError, platform, "bin ^3%s^R has no path", bin)
+		case filepath.IsAbs(relPath):
+			result.add(LintError, platform, "bin ^3%s^R path ^1%s^R must be relative to the "+
+				"package directory", bin, binPath)
+		case cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)):
+			result.add(LintError, platform, "bin ^3%s^R path ^1%s^R is outside the package "+
+				"directory", bin, binPath)
+		}
+	}
+
+	for algorithm, digests := range map[string]map[string]string{
+		checksum.SHA256: opts.Sha256,
+		checksum.SHA512: opts.Sha512,
+	} {
+		for key, digest := range digests {
+			if _, err := checksum.NewChecksum(algorithm, digest); err != nil {
+				result.add(LintError, platform, "%s checksum for ^3%s^R: %s", algorithm, key, err)
+			}
+		}
+	}
+
+	if opts.StripComponents < 0 {
+		result.add(LintError, platform, "strip-components can't be negative")
+	}
+
+	if opts.Subdir != "" && !strings.Contains(opts.Subdir, "${") {
+		cleaned := filepath.Clean(opts.Subdir)
+		if filepath.IsAbs(opts.Subdir) || cleaned == ".." ||
+			strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+			result.add(LintError, platform, "subdir ^1%s^R is outside the archive", opts.Subdir)
+		}
+	}
+}
+
+// declaredVars returns the names of the vars declared anywhere in the manifest, including its
+// with-version and with-platform blocks
+func declaredVars(conf *PackageManifestConfig) map[string]bool {
+	declared := make(map[string]bool)
+
+	opts := []PackageManifestOptions{conf.PackageManifestOptions}
+	for _, block := range conf.Versions {
+		opts = append(opts, block.PackageManifestOptions)
+	}
+	for _, block := range conf.Platforms {
+		opts = append(opts, block.PackageManifestOptions)
+	}
+
+	for _, opt := range opts {
+		for name := range opt.Vars {
+			declared[name] = true
+		}
+	}
+
+	return declared
+}
+
+func matchesSupportedPlatform(conf *PackageManifestConfig, block PackageManifestPlatformBlock) bool {
+	for _, platform := range SupportedPlatforms {
+		for _, match := range conf.MatchingPlatforms(platform.OS, platform.Arch) {
+			if match.Platform == block.Platform {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func lintURL(result *LintResult, platform string, field string, rawURL string) {
+	if strings.Contains(rawURL, "${") {
+		return
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		result.add(LintError, platform, "%s isn't a valid URL: %s", field, err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		result.add(LintWarning, platform, "%s ^1%s^R isn't an http(s) URL", field, rawURL)
+	}
+}
+
+func variableNames(vars map[string]interface{}) []string {
+	var names []string
+	for name := range vars {
+		names = append(names, fmt.Sprintf("${%s}", name))
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,132 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lintBase = `
+name = "tool"
+version = "1.2.0"
+source = "https://example.com/tool-${version}-${platform}.tar.gz"
+`
+
+func TestLintManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		severity string
+		problem  string
+	}{
+		{"valid", lintBase, "", ""},
+		{"valid bins", lintBase + `
+bins = {
+  "tool": "bin/tool",
+  "other": "${output}/bin/other${exe}"
+}`, "", ""},
+		{"valid blocks", lintBase + `
+vars = {
+  "flavor": "gnu"
+}
+with-version ">=1.0.0 <2" {
+  bins = {
+    "tool": "tool-${var.flavor}"
+  }
+}
+with-platform "darwin-*" {
+  strip-components = 1
+}`, "", ""},
+		{"name mismatch", `
+name = "other"
+version = "1.2.0"
+source = "https://example.com/tool"`, LintError, "doesn't match the file name"},
+		{"no version", `
+name = "tool"
+source = "https://example.com/tool"`, LintError, "no default version"},
+		{"range as default version", `
+name = "tool"
+version = "^1.2"
+source = "https://example.com/tool"`, LintError, "isn't a semver version"},
+		{"invalid versions", lintBase + `versions = ["1.0.0", "latest"]`, LintError,
+			"in versions isn't a semver version"},
+		{"no source", `
+name = "tool"
+version = "1.2.0"`, LintError, "no source"},
+		{"not http", `
+name = "tool"
+version = "1.2.0"
+source = "ftp://example.com/tool"`, LintWarning, "isn't an http(s) URL"},
+		{"invalid range", lintBase + `
+with-version "not a range" {
+  exec = "tool"
+}`, LintError, "with-version block has"},
+		{"unknown variable", lintBase + `exec = "${verison}"`, LintError,
+			"unknown template variable"},
+		{"undeclared var", lintBase + `exec = "${var.flavor}"`, LintError,
+			"unknown template variable"},
+		{"unknown function", lintBase + `exec = "${os|shout}"`, LintError, "shout"},
+		{"absolute bin", lintBase + `
+bins = {
+  "tool": "/usr/bin/tool"
+}`, LintError, "must be relative to the package directory"},
+		{"bin outside package", lintBase + `
+bins = {
+  "tool": "../tool"
+}`, LintError, "is outside the package directory"},
+		{"invalid bin name", lintBase + `
+bins = {
+  "bin/tool": "tool"
+}`, LintError, "isn't a valid file name"},
+		{"invalid checksum", lintBase + `
+sha256 = {
+  "linux-x64": "abc"
+}`, LintError, "checksum for"},
+		{"subdir outside archive", lintBase + `subdir = "../tool"`, LintError,
+			"is outside the archive"},
+		{"unsupported platform", lintBase + `
+with-platform "plan9-*" {
+  exec = "tool"
+}`, LintWarning, "doesn't match any supported platform"},
+		{"invalid platform fallback", lintBase + `
+platform-fallbacks = {
+  "darwin-arm64": "darwin"
+}`, LintError, "platform-fallbacks"},
+		{"unparsable", `name = `, LintError, "unable to parse manifest"},
+	}
+
+	for _, test := range tests {
+		file := filepath.Join(t.TempDir(), "tool.hcl")
+		if err := os.WriteFile(file, []byte(test.manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		result := LintManifest(file)
+
+		if test.problem == "" {
+			for _, problem := range result.Problems {
+				t.Errorf("%s: unexpected %s: %s", test.name, problem.Severity, problem.Message)
+			}
+			if len(result.Sources) != len(SupportedPlatforms) {
+				t.Errorf("%s: expected a source for every supported platform, got %v", test.name,
+					result.Sources)
+			}
+			continue
+		}
+
+		found := false
+		for _, problem := range result.Problems {
+			if problem.Severity == test.severity && strings.Contains(problem.Message, test.problem) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected %s containing %q, got %+v", test.name, test.severity,
+				test.problem, result.Problems)
+		}
+		if result.HasErrors() != (test.severity == LintError) {
+			t.Errorf("%s: expected HasErrors to be %t", test.name, test.severity == LintError)
+		}
+	}
+}
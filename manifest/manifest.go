@@ -2,18 +2,24 @@ package manifest
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strings"
 
 	"github.com/alecthomas/colour"
 	"github.com/alecthomas/hcl"
 	"github.com/blang/semver/v4"
 	"github.com/imdario/mergo"
+	"github.com/josephschmitt/hvm/auth"
+	"github.com/josephschmitt/hvm/checksum"
+	"github.com/josephschmitt/hvm/offline"
 	"github.com/josephschmitt/hvm/paths"
 	"github.com/josephschmitt/hvm/repos"
 	log "github.com/sirupsen/logrus"
-	"github.com/valyala/fasttemplate"
 )
 
 type PackageManifestOptions struct {
@@ -23,6 +29,41 @@ type PackageManifestOptions struct {
 	Source  string            `hcl:"source,optional"`
 	Extract string            `hcl:"extract,optional"`
 	Test    string            `hcl:"test,optional"`
+
+	// Options for the built-in archive extractor, used when no extract command is set
+	StripComponents int    `hcl:"strip-components,optional"`
+	Subdir          string `hcl:"subdir,optional"`
+
+	// Expected digests of the downloaded source, keyed by platform (e.g. "linux-x64"). The "*" key
+	// matches any platform.
+	Sha256 map[string]string `hcl:"sha256,optional"`
+	Sha512 map[string]string `hcl:"sha512,optional"`
+
+	// Variables available to the manifest as ${var.name}, e.g. an archive extension that a
+	// with-platform block changes
+	Vars map[string]string `hcl:"vars,optional"`
+}
+
+// GetChecksum returns the strongest digest declared for the given platform, or nil if the manifest
+// doesn't declare one
+func (opts *PackageManifestOptions) GetChecksum(platform string) (*checksum.Checksum, error) {
+	digests := []struct {
+		algorithm string
+		values    map[string]string
+	}{
+		{checksum.SHA512, opts.Sha512},
+		{checksum.SHA256, opts.Sha256},
+	}
+
+	for _, digest := range digests {
+		for _, key := range []string{platform, "*"} {
+			if value, ok := digest.values[key]; ok && value != "" {
+				return checksum.NewChecksum(digest.algorithm, value)
+			}
+		}
+	}
+
+	return nil, nil
 }
 
 // PackageManifest contains the parsed result of the .hcl config file for a package. It's used to
@@ -38,10 +79,7 @@ func NewPackageManfiest(
 	ctx *PackageManifestContext,
 	overrides *PackageManifestOptions,
 ) (*PackageManifest, error) {
-	man := &PackageManifest{Name: name}
-	if err := man.UpdateRepos(); err != nil {
-		return nil, err
-	}
+	man := &PackageManifest{Name: repos.PackageName(name)}
 
 	conf := &PackageManifestConfig{Name: name}
 	if err := conf.Parse(); err != nil {
@@ -71,23 +109,39 @@ func NewPackageManfiest(
 	return man, nil
 }
 
+// UpdateRepos fetches any package repository that hasn't been fetched yet. Repositories are also
+// fetched as they're searched for a package, so this is only needed to fetch all of them upfront.
 func (man *PackageManifest) UpdateRepos() error {
-	if _, err := os.Stat(paths.AppPaths.ReposDirectory); os.IsNotExist(err) {
-		loader := repos.NewGitRepoLoader("", "")
-		if err := loader.Get(); err != nil {
-			return err
-		}
-	}
-
+	repos.GetMissing()
 	return nil
 }
 
+// versionPattern matches anything that looks like a version in an upstream versions listing
+var versionPattern = regexp.MustCompile(`\bv?\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?\b`)
+
+const maxVersionsListing = 16 << 20
+
 type PackageManifestConfig struct {
 	Name        string `hcl:"name"`
 	Description string `hcl:"description,optional"`
 
 	PackageManifestOptions
-	Versions []PackageManifestVersionBlock `hcl:"with-version,block,optional"`
+	Versions  []PackageManifestVersionBlock  `hcl:"with-version,block,optional"`
+	Platforms []PackageManifestPlatformBlock `hcl:"with-platform,block,optional"`
+
+	// Names to use for ${os} and ${arch} (and so ${platform}) instead of Go's, keyed by Go's name,
+	// e.g. { "darwin": "macos" }
+	OSAliases   map[string]string `hcl:"os-aliases,optional"`
+	ArchAliases map[string]string `hcl:"arch-aliases,optional"`
+
+	// Platforms to render the manifest for instead when running on a platform the manifest has no
+	// with-platform block for, e.g. { "darwin-arm64": "darwin-x64" } to run under Rosetta
+	PlatformFallbacks map[string]string `hcl:"platform-fallbacks,optional"`
+
+	// Versions of the package that ranges in the use map can resolve to, in addition to the default
+	// version. VersionsURL points at an upstream listing (any text or JSON) to scrape versions from.
+	AvailableVersions []string `hcl:"versions,optional"`
+	VersionsURL       string   `hcl:"versions-url,optional"`
 }
 
 func NewPackageManfiestConfig(name string) (*PackageManifestConfig, error) {
@@ -113,23 +167,35 @@ func (conf *PackageManifestConfig) Merge(
 		conf.Version = ctx.Version
 	}
 
-	ctxVer, err := semver.Parse(conf.Version)
+	versions, err := conf.MatchingVersions(conf.Version)
 	if err != nil {
 		return err
 	}
 
-	for _, version := range conf.Versions {
-		expectedRange, err := semver.ParseRange(version.Version)
-		if err != nil || expectedRange == nil || !expectedRange(ctxVer) {
-			continue
+	for _, version := range versions {
+		if err := mergo.Merge(&conf.PackageManifestOptions, version.PackageManifestOptions, mergo.WithOverride); err != nil {
+			log.Error(err)
+			return err
 		}
+	}
 
-		if err := mergo.Merge(&conf.PackageManifestOptions, version.PackageManifestOptions, mergo.WithOverride); err != nil {
+	target := conf.TargetContext(ctx)
+	for _, platform := range conf.MatchingPlatforms(target.OS, target.Arch) {
+		if err := mergo.Merge(&conf.PackageManifestOptions, platform.PackageManifestOptions, mergo.WithOverride); err != nil {
 			log.Error(err)
 			return err
 		}
 	}
 
+	// The fallback's download is what gets installed, so its checksum applies to this platform too
+	if target.Platform != ctx.Platform {
+		for _, digests := range []map[string]string{conf.Sha256, conf.Sha512} {
+			if _, ok := digests[ctx.Platform]; !ok && digests[target.Platform] != "" {
+				digests[ctx.Platform] = digests[target.Platform]
+			}
+		}
+	}
+
 	if overrides != nil {
 		if err := mergo.Merge(&conf.PackageManifestOptions, overrides, mergo.WithOverride); err != nil {
 			log.Error(err)
@@ -140,6 +206,160 @@ func (conf *PackageManifestConfig) Merge(
 	return nil
 }
 
+// MatchingVersions returns the with-version blocks whose range includes version, in the order they
+// are applied
+func (conf *PackageManifestConfig) MatchingVersions(
+	version string,
+) ([]PackageManifestVersionBlock, error) {
+	ctxVer, err := semver.Parse(version)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []PackageManifestVersionBlock
+	for _, block := range conf.Versions {
+		expectedRange, err := ParseConstraint(block.Version)
+		if err != nil || expectedRange == nil || !expectedRange(ctxVer) {
+			continue
+		}
+
+		matches = append(matches, block)
+	}
+
+	return matches, nil
+}
+
+// MatchingPlatforms returns the with-platform blocks whose pattern matches the platform, in the
+// order they are applied
+func (conf *PackageManifestConfig) MatchingPlatforms(
+	goos string,
+	goarch string,
+) []PackageManifestPlatformBlock {
+	names := conf.platformNames(goos, goarch)
+
+	var matches []PackageManifestPlatformBlock
+	for _, block := range conf.Platforms {
+		for _, name := range names {
+			if ok, _ := path.Match(block.Platform, name); ok {
+				matches = append(matches, block)
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// platformNames returns every name a platform may be referred to by in a with-platform block: hvm's
+// (linux-x64), Go's (linux-amd64), the x-platform (linux-x86_64) and the manifest's aliased one
+func (conf *PackageManifestConfig) platformNames(goos string, goarch string) []string {
+	return []string{
+		PlatformFor(goos, goarch),
+		fmt.Sprintf("%s-%s", goos, goarch),
+		XPlatformFor(goos, goarch),
+		fmt.Sprintf("%s-%s", conf.osAlias(goos), conf.archAlias(goarch)),
+	}
+}
+
+// TargetContext returns the context to render the manifest with. This is ctx itself, unless the
+// manifest declares a fallback for its platform and has no with-platform block for it, in which
+// case the fallback chain is followed.
+func (conf *PackageManifestConfig) TargetContext(ctx *PackageManifestContext) *PackageManifestContext {
+	target := ctx
+	seen := make(map[string]bool)
+
+	for !seen[target.Platform] {
+		seen[target.Platform] = true
+
+		if len(conf.MatchingPlatforms(target.OS, target.Arch)) > 0 {
+			break
+		}
+
+		fallback := conf.fallbackFor(target.OS, target.Arch)
+		if fallback == "" {
+			break
+		}
+
+		goos, goarch, err := ParsePlatform(fallback)
+		if err != nil {
+			log.Warnf("Ignoring platform fallback for %s: %s", target.Platform, err)
+			break
+		}
+
+		log.Debugf(colour.Sprintf("Render ^3%s^R for ^6%s^R instead of ^6%s^R\n", conf.Name,
+			fallback, target.Platform))
+
+		target = NewPlatformManifestContext(ctx.Name, ctx.Version, goos, goarch)
+	}
+
+	return target
+}
+
+func (conf *PackageManifestConfig) fallbackFor(goos string, goarch string) string {
+	for _, name := range conf.platformNames(goos, goarch) {
+		if fallback := conf.PlatformFallbacks[name]; fallback != "" {
+			return fallback
+		}
+	}
+
+	return ""
+}
+
+func (conf *PackageManifestConfig) osAlias(goos string) string {
+	if alias := conf.OSAliases[goos]; alias != "" {
+		return alias
+	}
+
+	return goos
+}
+
+func (conf *PackageManifestConfig) archAlias(goarch string) string {
+	if alias := conf.ArchAliases[goarch]; alias != "" {
+		return alias
+	}
+
+	return goarch
+}
+
+// KnownVersions lists every version of the package the manifest knows about. Offline, versions
+// only listed at versions-url aren't known.
+func (conf *PackageManifestConfig) KnownVersions() ([]string, error) {
+	var versions []string
+	if conf.Version != "" {
+		versions = append(versions, conf.Version)
+	}
+	versions = append(versions, conf.AvailableVersions...)
+
+	if conf.VersionsURL == "" {
+		return versions, nil
+	} else if offline.Enabled() {
+		log.Debugf(colour.Sprintf("Offline, not fetching versions of ^3%s^R from ^2%s^R\n",
+			conf.Name, conf.VersionsURL))
+		return versions, nil
+	}
+
+	log.Debugf(colour.Sprintf("Fetching versions of ^3%s^R from ^2%s^R\n", conf.Name,
+		conf.VersionsURL))
+
+	resp, err := auth.Get(conf.VersionsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf(colour.Sprintf("failed to fetch versions of ^3%s^R from ^1%s^R: %s",
+			conf.Name, conf.VersionsURL, resp.Status))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxVersionsListing))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(versions, versionPattern.FindAllString(string(data), -1)...), nil
+}
+
 func (conf *PackageManifestConfig) Parse() error {
 	data, err := conf.GetManifestTemplate(conf.Name)
 	if err != nil {
@@ -152,7 +372,7 @@ func (conf *PackageManifestConfig) Parse() error {
 func (conf *PackageManifestConfig) Render(ctx *PackageManifestContext) error {
 	// Use version declared in the manifest as a "default"
 	if ctx.Version == "" {
-		ctx.Version = conf.Version
+		ctx.SetVersion(conf.Version)
 	}
 
 	data, err := hcl.Marshal(conf)
@@ -160,31 +380,52 @@ func (conf *PackageManifestConfig) Render(ctx *PackageManifestContext) error {
 		return err
 	}
 
-	t := fasttemplate.New(string(data), "${", "}")
-	s := t.ExecuteString(map[string]interface{}{
-		"version":    ctx.Version,
-		"platform":   ctx.Platform,
-		"x-platform": ctx.XPlatform,
-		"os":         runtime.GOOS,
-		"arch":       runtime.GOARCH,
-		"output":     ctx.OutputDir,
-	})
+	target := conf.TargetContext(ctx)
+	vars := target.Variables()
+
+	// Aliases only change how the platform is spelled in the manifest, not which platform it is
+	if len(conf.OSAliases) > 0 || len(conf.ArchAliases) > 0 {
+		osName := conf.osAlias(target.OS)
+		vars["os"] = osName
+		vars["arch"] = conf.archAlias(target.Arch)
+		vars["x-platform"] = fmt.Sprintf("%s-%s", osName, archName(xarch, target.Arch))
+
+		if alias := conf.ArchAliases[target.Arch]; alias != "" {
+			vars["platform"] = fmt.Sprintf("%s-%s", osName, alias)
+		} else {
+			vars["platform"] = fmt.Sprintf("%s-%s", osName, archName(arch, target.Arch))
+		}
+	}
+
+	// Vars may refer to the built-in variables, but not to each other
+	for name, value := range conf.Vars {
+		rendered, err := renderTemplate(value, vars)
+		if err != nil {
+			return err
+		}
+		vars[varPrefix+name] = rendered
+	}
+
+	s, err := renderTemplate(string(data), vars)
+	if err != nil {
+		return err
+	}
 
 	return hcl.Unmarshal([]byte(s), conf)
 }
 
+// GetManifestTemplate reads the raw manifest of a package from the first repository that has it.
+// The name may be prefixed with a repository name, as in "repo/pkg".
 func (*PackageManifestConfig) GetManifestTemplate(name string) ([]byte, error) {
-	configFilePath := filepath.Join(paths.AppPaths.ReposDirectory, name+".hcl")
-	data, err := os.ReadFile(configFilePath)
-	if os.IsNotExist(err) {
-		return nil, fmt.Errorf(colour.Sprintf("no hvm-package found named \"^2%s^R\" at ^6%s^R\n"+
-			"Try updating your packages, or contributing a new one for \"^2%s^R\".",
-			name, configFilePath, name))
-	} else if err != nil {
+	loader, err := repos.FindPackage(name)
+	if err != nil {
 		return nil, err
 	}
 
-	return data, nil
+	configFilePath := filepath.Join(loader.GetPath(), repos.PackageName(name)+".hcl")
+	log.Debugf(colour.Sprintf("Read manifest for ^3%s^R from ^6%s^R\n", name, configFilePath))
+
+	return os.ReadFile(configFilePath)
 }
 
 type PackageManifestVersionBlock struct {
@@ -192,22 +433,64 @@ type PackageManifestVersionBlock struct {
 	PackageManifestOptions
 }
 
+// PackageManifestPlatformBlock overrides options on platforms matching its glob pattern, e.g.
+// "linux-*" or "darwin-arm64"
+type PackageManifestPlatformBlock struct {
+	Platform string `hcl:"platform,label"`
+	PackageManifestOptions
+}
+
+// ParsePackageSpec splits a "name@version" package spec into its parts. The version is empty if
+// the spec doesn't include one.
+func ParsePackageSpec(spec string) (string, string) {
+	if i := strings.LastIndex(spec, "@"); i > 0 {
+		return spec[:i], spec[i+1:]
+	}
+
+	return spec, ""
+}
+
 type PackageManifestContext struct {
+	Name      string
 	Version   string
+	OS        string
+	Arch      string
 	Platform  string
 	XPlatform string
+	Libc      string
 	OutputDir string
 }
 
 func NewManifestContext(name string, version string) *PackageManifestContext {
-	platform := Platform()
+	return NewPlatformManifestContext(name, version, runtime.GOOS, runtime.GOARCH)
+}
 
-	return &PackageManifestContext{
-		Version:   version,
-		Platform:  platform,
-		XPlatform: XPlatform(platform),
-		OutputDir: filepath.Join(paths.AppPaths.PkgsDirectory, name, version),
+// NewPlatformManifestContext creates a context for rendering a manifest for a platform other than
+// the one hvm is running on
+func NewPlatformManifestContext(
+	name string,
+	version string,
+	goos string,
+	goarch string,
+) *PackageManifestContext {
+	ctx := &PackageManifestContext{
+		Name:      repos.PackageName(name),
+		OS:        goos,
+		Arch:      goarch,
+		Platform:  PlatformFor(goos, goarch),
+		XPlatform: XPlatformFor(goos, goarch),
+		Libc:      libcFor(goos, goarch),
 	}
+	ctx.SetVersion(version)
+
+	return ctx
+}
+
+// SetVersion sets the version of the package, and the output directory that version is installed
+// into
+func (ctx *PackageManifestContext) SetVersion(version string) {
+	ctx.Version = version
+	ctx.OutputDir = filepath.Join(paths.AppPaths.PkgsDirectory, ctx.Name, version)
 }
 
 var arch = map[string]string{
@@ -215,8 +498,53 @@ var arch = map[string]string{
 	"arm64": "arm64",
 }
 
+// SupportedPlatforms are the os/arch combinations hvm is released for
+var SupportedPlatforms = []struct {
+	OS   string
+	Arch string
+}{
+	{"linux", "amd64"},
+	{"linux", "arm64"},
+	{"darwin", "amd64"},
+	{"darwin", "arm64"},
+}
+
 func Platform() string {
-	return fmt.Sprintf("%s-%s", runtime.GOOS, arch[runtime.GOARCH])
+	return PlatformFor(runtime.GOOS, runtime.GOARCH)
+}
+
+func PlatformFor(goos string, goarch string) string {
+	return fmt.Sprintf("%s-%s", goos, archName(arch, goarch))
+}
+
+// archName looks up the name of an arch in names, falling back to Go's name for it
+func archName(names map[string]string, goarch string) string {
+	if name := names[goarch]; name != "" {
+		return name
+	}
+
+	return goarch
+}
+
+// ParsePlatform splits a platform into Go's os and arch names. The arch may be spelled the way
+// ${platform}, ${x-platform} or Go spell it, e.g. "darwin-x64", "darwin-x86_64" or "darwin-amd64".
+func ParsePlatform(platform string) (string, string, error) {
+	parts := strings.SplitN(platform, "-", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf(colour.Sprintf("invalid platform ^1%s^R, expected os-arch",
+			platform))
+	}
+
+	goos, name := parts[0], parts[1]
+	for _, names := range []map[string]string{arch, xarch} {
+		for goarch, archName := range names {
+			if archName == name {
+				return goos, goarch, nil
+			}
+		}
+	}
+
+	return goos, name, nil
 }
 
 var xarch = map[string]string{
@@ -225,5 +553,9 @@ var xarch = map[string]string{
 }
 
 func XPlatform(platform string) string {
-	return fmt.Sprintf("%s-%s", runtime.GOOS, xarch[runtime.GOARCH])
+	return XPlatformFor(runtime.GOOS, runtime.GOARCH)
+}
+
+func XPlatformFor(goos string, goarch string) string {
+	return fmt.Sprintf("%s-%s", goos, archName(xarch, goarch))
 }
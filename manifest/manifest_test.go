@@ -0,0 +1,242 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/hcl"
+)
+
+func TestParsePackageSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		name    string
+		version string
+	}{
+		{"node", "node", ""},
+		{"node@18.1.0", "node", "18.1.0"},
+		{"node@^18", "node", "^18"},
+		{"node@", "node", ""},
+		{"repo/node@1.0.0", "repo/node", "1.0.0"},
+		{"@scope/pkg@1.0.0", "@scope/pkg", "1.0.0"},
+		{"@scope/pkg", "@scope/pkg", ""},
+	}
+
+	for _, test := range tests {
+		name, version := ParsePackageSpec(test.spec)
+		if name != test.name || version != test.version {
+			t.Errorf("ParsePackageSpec(%q) = %q, %q, expected %q, %q", test.spec, name, version,
+				test.name, test.version)
+		}
+	}
+}
+
+func parseManifest(t *testing.T, manifest string) *PackageManifestConfig {
+	t.Helper()
+
+	conf := &PackageManifestConfig{}
+	if err := hcl.Unmarshal([]byte(manifest), conf); err != nil {
+		t.Fatal(err)
+	}
+
+	return conf
+}
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		platform string
+		os       string
+		arch     string
+		ok       bool
+	}{
+		{"darwin-x64", "darwin", "amd64", true},
+		{"darwin-x86_64", "darwin", "amd64", true},
+		{"darwin-amd64", "darwin", "amd64", true},
+		{"linux-arm64", "linux", "arm64", true},
+		{"linux-riscv64", "linux", "riscv64", true},
+		{"linux", "", "", false},
+		{"-x64", "", "", false},
+		{"linux-", "", "", false},
+	}
+
+	for _, test := range tests {
+		goos, goarch, err := ParsePlatform(test.platform)
+		if test.ok != (err == nil) {
+			t.Errorf("%s: expected ok=%t, got error %v", test.platform, test.ok, err)
+		} else if goos != test.os || goarch != test.arch {
+			t.Errorf("%s: expected %s/%s, got %s/%s", test.platform, test.os, test.arch, goos,
+				goarch)
+		}
+	}
+}
+
+func TestMatchingPlatforms(t *testing.T) {
+	conf := parseManifest(t, `
+name = "tool"
+os-aliases = {
+  "darwin": "macos"
+}
+with-platform "linux-*" {
+  exec = "linux"
+}
+with-platform "darwin-x86_64" {
+  exec = "x-platform"
+}
+with-platform "darwin-arm64" {
+  exec = "darwin-arm64"
+}
+with-platform "macos-*" {
+  exec = "alias"
+}
+with-platform "*-amd64" {
+  exec = "go"
+}
+`)
+
+	tests := []struct {
+		os       string
+		arch     string
+		expected string
+	}{
+		{"linux", "amd64", "linux-*,*-amd64"},
+		{"linux", "arm64", "linux-*"},
+		{"darwin", "amd64", "darwin-x86_64,macos-*,*-amd64"},
+		{"darwin", "arm64", "darwin-arm64,macos-*"},
+		{"windows", "386", ""},
+	}
+
+	for _, test := range tests {
+		var matches []string
+		for _, block := range conf.MatchingPlatforms(test.os, test.arch) {
+			matches = append(matches, block.Platform)
+		}
+
+		if actual := strings.Join(matches, ","); actual != test.expected {
+			t.Errorf("%s-%s: expected %s, got %s", test.os, test.arch, test.expected, actual)
+		}
+	}
+}
+
+func TestTargetContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		platform string
+		expected string
+	}{
+		{"no fallback", `name = "tool"`, "darwin-arm64", "darwin-arm64"},
+		{"fallback", `
+name = "tool"
+platform-fallbacks = {
+  "darwin-arm64": "darwin-x64"
+}`, "darwin-arm64", "darwin-x64"},
+		{"platform block wins over fallback", `
+name = "tool"
+platform-fallbacks = {
+  "darwin-arm64": "darwin-x64"
+}
+with-platform "darwin-arm64" {
+  exec = "tool"
+}`, "darwin-arm64", "darwin-arm64"},
+		{"chain", `
+name = "tool"
+platform-fallbacks = {
+  "linux-arm64": "linux-amd64",
+  "linux-x64": "linux-x86_64"
+}
+with-platform "linux-x86_64" {
+  exec = "tool"
+}`, "linux-arm64", "linux-x64"},
+		{"cycle", `
+name = "tool"
+platform-fallbacks = {
+  "linux-arm64": "linux-x64",
+  "linux-x64": "linux-arm64"
+}`, "linux-arm64", "linux-arm64"},
+		{"invalid fallback", `
+name = "tool"
+platform-fallbacks = {
+  "linux-arm64": "linux"
+}`, "linux-arm64", "linux-arm64"},
+	}
+
+	for _, test := range tests {
+		goos, goarch, err := ParsePlatform(test.platform)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conf := parseManifest(t, test.manifest)
+		ctx := NewPlatformManifestContext("tool", "1.0.0", goos, goarch)
+		if actual := conf.TargetContext(ctx).Platform; actual != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestMergePlatforms(t *testing.T) {
+	const manifest = `
+name = "tool"
+version = "1.0.0"
+source = "https://example.com/tool-${version}-${platform}.tar.gz"
+exec = "tool"
+arch-aliases = {
+  "amd64": "intel"
+}
+platform-fallbacks = {
+  "darwin-arm64": "darwin-x64"
+}
+sha256 = {
+  "darwin-x64": "0000000000000000000000000000000000000000000000000000000000000000"
+}
+with-platform "linux-*" {
+  source = "https://example.com/linux/tool-${version}-${arch}.tar.gz"
+}
+with-platform "linux-arm64" {
+  exec = "tool-arm"
+}
+`
+
+	tests := []struct {
+		platform string
+		source   string
+		exec     string
+		checksum bool
+	}{
+		{"linux-x64", "https://example.com/linux/tool-1.0.0-intel.tar.gz", "tool", false},
+		{"linux-arm64", "https://example.com/linux/tool-1.0.0-arm64.tar.gz", "tool-arm", false},
+		{"darwin-x64", "https://example.com/tool-1.0.0-darwin-intel.tar.gz", "tool", true},
+		{"darwin-arm64", "https://example.com/tool-1.0.0-darwin-intel.tar.gz", "tool", true},
+	}
+
+	for _, test := range tests {
+		goos, goarch, err := ParsePlatform(test.platform)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conf := parseManifest(t, manifest)
+		ctx := NewPlatformManifestContext("tool", "", goos, goarch)
+		if err := conf.Merge(nil, ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := conf.Render(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if conf.Source != test.source {
+			t.Errorf("%s: expected source %s, got %s", test.platform, test.source, conf.Source)
+		}
+		if conf.Exec != test.exec {
+			t.Errorf("%s: expected exec %s, got %s", test.platform, test.exec, conf.Exec)
+		}
+
+		sum, err := conf.GetChecksum(test.platform)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (sum != nil) != test.checksum {
+			t.Errorf("%s: expected a checksum: %t", test.platform, test.checksum)
+		}
+	}
+}
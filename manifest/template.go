@@ -0,0 +1,176 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/colour"
+	"github.com/blang/semver/v4"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasttemplate"
+)
+
+// varPrefix prefixes the names of variables declared in a manifest's vars map, as in ${var.ext}
+const varPrefix = "var."
+
+// templateFunc transforms the value of a placeholder, as in ${os|upper} or ${version|replace:.:_}
+type templateFunc struct {
+	args int
+	fn   func(value string, args []string) string
+}
+
+var templateFuncs = map[string]templateFunc{
+	"upper": {0, func(value string, _ []string) string { return strings.ToUpper(value) }},
+	"lower": {0, func(value string, _ []string) string { return strings.ToLower(value) }},
+	"title": {0, func(value string, _ []string) string {
+		if value == "" {
+			return value
+		}
+		return strings.ToUpper(value[:1]) + value[1:]
+	}},
+	"trimprefix": {1, func(value string, args []string) string {
+		return strings.TrimPrefix(value, args[0])
+	}},
+	"trimsuffix": {1, func(value string, args []string) string {
+		return strings.TrimSuffix(value, args[0])
+	}},
+	"replace": {2, func(value string, args []string) string {
+		return strings.ReplaceAll(value, args[0], args[1])
+	}},
+	"default": {1, func(value string, args []string) string {
+		if value == "" {
+			return args[0]
+		}
+		return value
+	}},
+}
+
+type templateCall struct {
+	name string
+	args []string
+}
+
+// parseTag splits a placeholder into the variable it refers to and the functions its value is
+// piped through
+func parseTag(tag string) (string, []templateCall, error) {
+	parts := strings.Split(tag, "|")
+
+	var calls []templateCall
+	for _, part := range parts[1:] {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+
+		fn, ok := templateFuncs[fields[0]]
+		if !ok {
+			return "", nil, fmt.Errorf(colour.Sprintf("unknown template function ^1%s^R in ^1${%s}^R",
+				fields[0], tag))
+		}
+
+		if len(fields)-1 != fn.args {
+			return "", nil, fmt.Errorf(colour.Sprintf("template function ^1%s^R in ^1${%s}^R takes "+
+				"%d argument(s)", fields[0], tag, fn.args))
+		}
+
+		calls = append(calls, templateCall{name: fields[0], args: fields[1:]})
+	}
+
+	return strings.TrimSpace(parts[0]), calls, nil
+}
+
+// renderTemplate substitutes the ${...} placeholders in text. Placeholders for unknown variables
+// render as empty strings.
+func renderTemplate(text string, vars map[string]interface{}) (string, error) {
+	return fasttemplate.ExecuteFuncStringWithErr(text, "${", "}",
+		func(w io.Writer, tag string) (int, error) {
+			name, calls, err := parseTag(tag)
+			if err != nil {
+				return 0, err
+			}
+
+			value, ok := vars[name].(string)
+			if !ok {
+				log.Debugf(colour.Sprintf("Unknown template variable ^1${%s}^R\n", name))
+			}
+
+			for _, call := range calls {
+				value = templateFuncs[call.name].fn(value, call.args)
+			}
+
+			return w.Write([]byte(value))
+		})
+}
+
+// Variables returns the values of the ${...} placeholders manifests can use
+func (ctx *PackageManifestContext) Variables() map[string]interface{} {
+	vars := map[string]interface{}{
+		"version":    ctx.Version,
+		"platform":   ctx.Platform,
+		"x-platform": ctx.XPlatform,
+		"os":         ctx.OS,
+		"arch":       ctx.Arch,
+		"output":     ctx.OutputDir,
+		"libc":       ctx.Libc,
+		"exe":        "",
+
+		"version.major":      "",
+		"version.minor":      "",
+		"version.patch":      "",
+		"version.prerelease": "",
+		"version.build":      "",
+		"version.bare":       strings.TrimPrefix(ctx.Version, "v"),
+	}
+
+	if ctx.OS == "windows" {
+		vars["exe"] = ".exe"
+	}
+
+	if v, err := semver.ParseTolerant(ctx.Version); err == nil && ctx.Version != "" {
+		var pre []string
+		for _, part := range v.Pre {
+			pre = append(pre, part.String())
+		}
+
+		vars["version.major"] = fmt.Sprint(v.Major)
+		vars["version.minor"] = fmt.Sprint(v.Minor)
+		vars["version.patch"] = fmt.Sprint(v.Patch)
+		vars["version.prerelease"] = strings.Join(pre, ".")
+		vars["version.build"] = strings.Join(v.Build, ".")
+	}
+
+	return vars
+}
+
+// libcFor returns the C library packages for a platform are built against: "musl" or "glibc" on
+// linux, and empty elsewhere. Only the host's libc can be detected, other linux platforms are
+// assumed to use glibc.
+func libcFor(goos string, goarch string) string {
+	if goos != "linux" {
+		return ""
+	}
+
+	if goos == runtime.GOOS && goarch == runtime.GOARCH {
+		return hostLibc()
+	}
+
+	return "glibc"
+}
+
+var (
+	detectLibc   sync.Once
+	detectedLibc string
+)
+
+func hostLibc() string {
+	detectLibc.Do(func() {
+		detectedLibc = "glibc"
+
+		if matches, _ := filepath.Glob("/lib/ld-musl-*"); len(matches) > 0 {
+			detectedLibc = "musl"
+		}
+	})
+
+	return detectedLibc
+}
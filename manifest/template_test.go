@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag   string
+		name  string
+		calls string
+		ok    bool
+	}{
+		{"version", "version", "", true},
+		{" version ", "version", "", true},
+		{"os|upper", "os", "upper", true},
+		{"version|replace:.:_|trimprefix:v", "version", "replace .,_ trimprefix v", true},
+		{"libc | default:gnu", "libc", "default gnu", true},
+		{"os|shout", "", "", false},
+		{"os|replace:a", "", "", false},
+		{"os|upper:a", "", "", false},
+	}
+
+	for _, test := range tests {
+		name, calls, err := parseTag(test.tag)
+		if test.ok != (err == nil) {
+			t.Errorf("%q: expected ok=%t, got error %v", test.tag, test.ok, err)
+			continue
+		}
+
+		var described []string
+		for _, call := range calls {
+			described = append(described, strings.TrimSpace(call.name+" "+
+				strings.Join(call.args, ",")))
+		}
+
+		if name != test.name || strings.Join(described, " ") != test.calls {
+			t.Errorf("%q: expected %s with %q, got %s with %q", test.tag, test.name, test.calls,
+				name, described)
+		}
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	vars := map[string]interface{}{
+		"version": "v1.2.3",
+		"os":      "darwin",
+		"libc":    "",
+	}
+
+	tests := []struct {
+		text     string
+		expected string
+		ok       bool
+	}{
+		{"tool-${version}", "tool-v1.2.3", true},
+		{"${os|upper}-${os|title}", "DARWIN-Darwin", true},
+		{"${version|trimprefix:v|replace:.:_}", "1_2_3", true},
+		{"tool.${os|trimsuffix:win|lower}", "tool.dar", true},
+		{"${libc|default:gnu}", "gnu", true},
+		{"${unknown}", "", true},
+		{"no placeholders", "no placeholders", true},
+		{"${os|shout}", "", false},
+	}
+
+	for _, test := range tests {
+		actual, err := renderTemplate(test.text, vars)
+		if test.ok != (err == nil) {
+			t.Errorf("%q: expected ok=%t, got error %v", test.text, test.ok, err)
+		} else if actual != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.text, test.expected, actual)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		version  string
+		os       string
+		expected map[string]string
+	}{
+		{"v1.2.3", "linux", map[string]string{
+			"version":            "v1.2.3",
+			"version.bare":       "1.2.3",
+			"version.major":      "1",
+			"version.minor":      "2",
+			"version.patch":      "3",
+			"version.prerelease": "",
+			"exe":                "",
+			"platform":           "linux-x64",
+			"x-platform":         "linux-x86_64",
+		}},
+		{"2.0.0-rc.1+build.5", "windows", map[string]string{
+			"version.major":      "2",
+			"version.prerelease": "rc.1",
+			"version.build":      "build.5",
+			"exe":                ".exe",
+			"libc":               "",
+		}},
+		{"latest", "darwin", map[string]string{
+			"version":       "latest",
+			"version.bare":  "latest",
+			"version.major": "",
+		}},
+	}
+
+	for _, test := range tests {
+		vars := NewPlatformManifestContext("tool", test.version, test.os, "amd64").Variables()
+		for name, expected := range test.expected {
+			if vars[name] != expected {
+				t.Errorf("%s on %s: expected ${%s} to be %q, got %q", test.version, test.os, name,
+					expected, vars[name])
+			}
+		}
+	}
+}
+
+func TestLibc(t *testing.T) {
+	if libc := libcFor("darwin", "arm64"); libc != "" {
+		t.Errorf("expected no libc on darwin, got %s", libc)
+	}
+
+	// Only the host's libc is detected, other linux platforms are assumed to use glibc
+	other := "arm64"
+	if runtime.GOOS == "linux" && runtime.GOARCH == other {
+		other = "amd64"
+	}
+	if libc := libcFor("linux", other); libc != "glibc" {
+		t.Errorf("expected glibc on linux-%s, got %s", other, libc)
+	}
+}
+
+func TestRenderVars(t *testing.T) {
+	conf := parseManifest(t, `
+name = "tool"
+version = "v1.20.3"
+source = "https://example.com/${version.major}.${version.minor}/tool-${var.file}"
+vars = {
+  "file": "${version.bare}-${os|title}.tar.gz"
+}
+`)
+
+	ctx := NewPlatformManifestContext("tool", "", "linux", "amd64")
+	if err := conf.Render(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "https://example.com/1.20/tool-1.20.3-Linux.tar.gz"
+	if conf.Source != expected {
+		t.Errorf("expected %s, got %s", expected, conf.Source)
+	}
+}
@@ -0,0 +1,95 @@
+package mirror
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/colour"
+)
+
+// Mirror rewrites the source URLs of packages to download them from somewhere else, e.g. an
+// artifact proxy. Sources starting with Prefix have it replaced by URL. Alternatively, sources
+// matching the regular expression Match are replaced by URL, which may refer to submatches as in
+// regexp.Regexp.Expand, e.g. "$1". Packages limits the mirror to the named packages.
+type Mirror struct {
+	Name     string   `hcl:"name,label"`
+	Prefix   string   `hcl:"prefix,optional"`
+	Match    string   `hcl:"match,optional"`
+	URL      string   `hcl:"url"`
+	Packages []string `hcl:"packages,optional"`
+
+	pattern *regexp.Regexp
+}
+
+// Validate checks the mirror has exactly one of prefix or match, and compiles its match pattern
+func (m *Mirror) Validate() error {
+	switch {
+	case m.Prefix == "" && m.Match == "":
+		return fmt.Errorf(colour.Sprintf("mirror ^3%s^R needs a prefix or match", m.Name))
+	case m.Prefix != "" && m.Match != "":
+		return fmt.Errorf(colour.Sprintf("mirror ^3%s^R can't have both a prefix and match", m.Name))
+	case m.Match != "":
+		pattern, err := regexp.Compile(m.Match)
+		if err != nil {
+			return fmt.Errorf(colour.Sprintf("mirror ^3%s^R has an invalid match ^1%s^R: %s", m.Name,
+				m.Match, err))
+		}
+		m.pattern = pattern
+	}
+
+	return nil
+}
+
+// Rewrite returns the source URL of a package as downloaded through the mirror, and whether the
+// mirror applies to it at all
+func (m *Mirror) Rewrite(name string, source string) (string, bool) {
+	if len(m.Packages) > 0 && !contains(m.Packages, name) {
+		return "", false
+	}
+
+	if m.Prefix != "" {
+		if !strings.HasPrefix(source, m.Prefix) {
+			return "", false
+		}
+
+		return m.URL + strings.TrimPrefix(source, m.Prefix), true
+	}
+
+	if m.pattern == nil {
+		if err := m.Validate(); err != nil {
+			return "", false
+		}
+	}
+
+	match := m.pattern.FindStringSubmatchIndex(source)
+	if match == nil {
+		return "", false
+	}
+
+	// Only the matched part of the source is replaced, like regexp.Regexp.ReplaceAllString
+	rewritten := m.pattern.ExpandString(nil, m.URL, source, match)
+	return source[:match[0]] + string(rewritten) + source[match[1]:], true
+}
+
+// Rewrite returns the source URL of a package as downloaded through the first mirror that applies
+// to it. The source is returned unchanged if no mirror applies.
+func Rewrite(mirrors []*Mirror, name string, source string) (string, *Mirror) {
+	for _, m := range mirrors {
+		if rewritten, ok := m.Rewrite(name, source); ok {
+			return rewritten, m
+		}
+	}
+
+	return source, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
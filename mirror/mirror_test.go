@@ -0,0 +1,73 @@
+package mirror
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		mirror Mirror
+		ok     bool
+	}{
+		{Mirror{Name: "proxy", Prefix: "https://github.com/", URL: "https://proxy/"}, true},
+		{Mirror{Name: "proxy", Match: `^https://([^/]+)/`, URL: "https://proxy/$1/"}, true},
+		{Mirror{Name: "proxy", URL: "https://proxy/"}, false},
+		{Mirror{Name: "proxy", Prefix: "https://github.com/", Match: "github",
+			URL: "https://proxy/"}, false},
+		{Mirror{Name: "proxy", Match: `(unclosed`, URL: "https://proxy/"}, false},
+	}
+
+	for _, test := range tests {
+		err := test.mirror.Validate()
+		if test.ok != (err == nil) {
+			t.Errorf("%+v: expected ok=%t, got error %v", test.mirror, test.ok, err)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	mirrors := []*Mirror{
+		{Name: "node", Prefix: "https://nodejs.org/dist/", URL: "https://proxy/node/",
+			Packages: []string{"node"}},
+		{Name: "github", Match: `^https://github\.com/([^/]+)/([^/]+)/releases/download/`,
+			URL: "https://proxy/gh/${1}/${2}/"},
+		{Name: "everything", Prefix: "https://", URL: "https://proxy/any/"},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		mirror   string
+	}{
+		{"node", "https://nodejs.org/dist/v18.0.0/node.tar.gz",
+			"https://proxy/node/v18.0.0/node.tar.gz", "node"},
+		{"other", "https://nodejs.org/dist/v18.0.0/node.tar.gz",
+			"https://proxy/any/nodejs.org/dist/v18.0.0/node.tar.gz", "everything"},
+		{"gh", "https://github.com/cli/cli/releases/download/v2.0.0/gh.tar.gz",
+			"https://proxy/gh/cli/cli/v2.0.0/gh.tar.gz", "github"},
+		{"local", "http://localhost/tool", "http://localhost/tool", ""},
+	}
+
+	for _, test := range tests {
+		rewritten, m := Rewrite(mirrors, test.name, test.source)
+
+		name := ""
+		if m != nil {
+			name = m.Name
+		}
+
+		if rewritten != test.expected || name != test.mirror {
+			t.Errorf("%s %s: expected %s through %q, got %s through %q", test.name, test.source,
+				test.expected, test.mirror, rewritten, name)
+		}
+	}
+
+	if _, m := Rewrite(nil, "node", "https://nodejs.org/dist/"); m != nil {
+		t.Errorf("expected no mirror to apply without mirrors, got %s", m.Name)
+	}
+
+	// A mirror with an invalid pattern never applies
+	invalid := &Mirror{Name: "invalid", Match: `(unclosed`, URL: "https://proxy/"}
+	if _, ok := invalid.Rewrite("node", "https://nodejs.org/dist/"); ok {
+		t.Error("expected a mirror with an invalid match not to apply")
+	}
+}
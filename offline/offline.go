@@ -0,0 +1,49 @@
+package offline
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/colour"
+)
+
+// EnvVar turns on offline mode when set, the same as passing --offline
+const EnvVar = "HVM_OFFLINE"
+
+var enabled bool
+
+// Enable turns offline mode on or off. While on, repositories aren't updated and packages are only
+// installed from the download cache.
+func Enable(on bool) {
+	enabled = on
+}
+
+// Enabled reports whether hvm must avoid the network
+func Enabled() bool {
+	return enabled
+}
+
+// Error is returned when something that isn't available locally would have to be fetched
+type Error struct {
+	// What names the thing that's missing, e.g. "pkg@version"
+	What string
+	// Source is where it would be fetched from
+	Source string
+}
+
+func (err *Error) Error() string {
+	return colour.Sprintf("not available offline: ^3%s^R (^6%s^R)", err.What, err.Source)
+}
+
+// IsError reports whether err, or any error it wraps, is an offline Error
+func IsError(err error) bool {
+	var offlineErr *Error
+	return errors.As(err, &offlineErr)
+}
+
+// Missing summarizes everything that couldn't be found offline in a single error
+func Missing(missing []string) error {
+	return fmt.Errorf(colour.Sprintf("not available offline: ^3%s^R\nRun again without --offline "+
+		"or %s to fetch them.", strings.Join(missing, "^R, ^3"), EnvVar))
+}
@@ -0,0 +1,49 @@
+package offline
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestIsError(t *testing.T) {
+	offlineErr := &Error{What: "node@18.0.0", Source: "https://example.com/node.tar.gz"}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"offline error", offlineErr, true},
+		{"wrapped", fmt.Errorf("unable to install: %w", offlineErr), true},
+		{"other error", fmt.Errorf("connection refused"), false},
+		{"nil", nil, false},
+	}
+
+	for _, test := range tests {
+		if actual := IsError(test.err); actual != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	err := &Error{What: "node@18.0.0", Source: "https://example.com/node.tar.gz"}
+
+	for _, expected := range []string{"not available offline", "node@18.0.0",
+		"https://example.com/node.tar.gz"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to contain %q", err.Error(), expected)
+		}
+	}
+}
+
+func TestMissing(t *testing.T) {
+	message := Missing([]string{"node@18.0.0", "deno@1.0.0"}).Error()
+
+	for _, expected := range []string{"node@18.0.0", "deno@1.0.0", "--offline", EnvVar} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("expected %q to contain %q", message, expected)
+		}
+	}
+}
@@ -12,7 +12,9 @@ import (
 var AppPaths *Paths
 
 const PackageRepository = "hvm-packages"
+const PackageRepositories = "hvm-repos"
 const PackageDownloads = "hvm-downloads"
+const PackageCache = "hvm-cache"
 
 type Paths struct {
 	GitRoot          string
@@ -22,6 +24,7 @@ type Paths struct {
 	TempDirectory    string
 	ReposDirectory   string
 	PkgsDirectory    string
+	CacheDirectory   string
 }
 
 func NewPaths() (*Paths, error) {
@@ -48,17 +51,27 @@ func NewPathsFromDir(dir string) (*Paths, error) {
 		tmpDir = "/tmp"
 	}
 
-	configDir := filepath.Join(u.HomeDir, ".hvm")
+	pths := NewPathsFromHome(dir, u.HomeDir, tmpDir)
+	pths.GitRoot = gitRoot
+
+	return pths, nil
+}
+
+// NewPathsFromHome returns the paths hvm uses when run from dir, for a user whose home directory
+// is homeDir
+func NewPathsFromHome(dir string, homeDir string, tmpDir string) *Paths {
+	configDir := filepath.Join(homeDir, ".hvm")
 
 	return &Paths{
-		GitRoot:          gitRoot,
+		GitRoot:          dir,
 		WorkingDirectory: dir,
-		HomeDirectory:    u.HomeDir,
+		HomeDirectory:    homeDir,
 		ConfigDirectory:  configDir,
 		TempDirectory:    filepath.Join(tmpDir, "hvm"),
-		ReposDirectory:   filepath.Join(configDir, PackageRepository),
+		ReposDirectory:   filepath.Join(configDir, PackageRepositories),
 		PkgsDirectory:    filepath.Join(configDir, PackageDownloads),
-	}, nil
+		CacheDirectory:   filepath.Join(configDir, PackageCache),
+	}
 }
 
 func FindDirGitRoot(dir string) string {
@@ -101,6 +114,21 @@ func (pths *Paths) ConfigFiles() []string {
 	return files
 }
 
+// ProjectConfigDir returns the .hvm directory of the current project: the closest one with a
+// config.hcl file between the working directory and the git root
+func (pths *Paths) ProjectConfigDir() string {
+	for _, dir := range []string{
+		filepath.Join(pths.WorkingDirectory, ".hvm"),
+		filepath.Join(pths.GitRoot, ".hvm"),
+	} {
+		if _, err := os.Stat(filepath.Join(dir, "config.hcl")); err == nil {
+			return dir
+		}
+	}
+
+	return filepath.Join(pths.GitRoot, ".hvm")
+}
+
 func (pths *Paths) ResolveDir(dir string) string {
 	var homeDirRegexp = regexp.MustCompile(`^~|(?:\${?HOME}?)(/.*)?`)
 	return homeDirRegexp.ReplaceAllString(dir, pths.HomeDirectory+"$1")
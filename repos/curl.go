@@ -0,0 +1,325 @@
+package repos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/auth"
+	"github.com/josephschmitt/hvm/download"
+	"github.com/josephschmitt/hvm/extract"
+	"github.com/josephschmitt/hvm/paths"
+	log "github.com/sirupsen/logrus"
+)
+
+// indexStateFile keeps the caching headers and revision of the last fetched index, so that
+// Update() can ask the server whether anything changed
+const indexStateFile = ".hvm-index.json"
+
+// CurlRepoLoader loads package manifests from a plain web server. The location is either a tarball
+// (or zip) of .hcl manifests, or a JSON index listing them:
+//
+//	{"manifests": ["node.hcl", "tools/jq.hcl"]}
+//
+// Manifest paths in the index are resolved relative to the index URL.
+type CurlRepoLoader struct {
+	Name     string
+	Location string
+	Path     string
+}
+
+type HTTPIndex struct {
+	Manifests []string `json:"manifests"`
+}
+
+type indexState struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Revision     string    `json:"revision"`
+	Updated      time.Time `json:"updated"`
+}
+
+// timeout is how long fetching from an http repository waits to connect or for a response
+var timeout = download.DefaultTimeout
+
+// SetTimeout sets how long fetching from an http repository waits to connect or for a response
+func SetTimeout(t time.Duration) {
+	if t > 0 {
+		timeout = t
+	}
+}
+
+func NewCurlRepoLoader(name string, url string) RepoLoader {
+	return &CurlRepoLoader{
+		Name:     name,
+		Location: url,
+		Path:     filepath.Join(paths.AppPaths.ReposDirectory, name),
+	}
+}
+
+func (curl *CurlRepoLoader) Get() error {
+	log.Debugf("Get repo %s at %s\n", curl.Name, curl.Location)
+	return curl.fetch(nil)
+}
+
+func (curl *CurlRepoLoader) Update() error {
+	log.Debugf("Update repo %s at %s\n", curl.Name, curl.Location)
+
+	state, err := curl.readState()
+	if err != nil {
+		return curl.fetch(nil)
+	}
+
+	return curl.fetch(state)
+}
+
+// fetch downloads the index, unless the server reports it hasn't changed since state was recorded
+func (curl *CurlRepoLoader) fetch(state *indexState) error {
+	req, err := http.NewRequest(http.MethodGet, curl.Location, nil)
+	if err != nil {
+		return err
+	}
+
+	if state != nil {
+		if state.ETag != "" {
+			req.Header.Set("If-None-Match", state.ETag)
+		}
+		if state.LastModified != "" {
+			req.Header.Set("If-Modified-Since", state.LastModified)
+		}
+	}
+
+	if err := auth.Authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := download.NewClient(timeout).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && state != nil {
+		state.Updated = time.Now()
+		if err := curl.writeState(curl.Path, state); err != nil {
+			return err
+		}
+
+		log.Infof("Repository %s already up-to-date, at %s\n", curl.Name, state.Revision)
+		return nil
+	} else if resp.StatusCode >= 400 {
+		return fmt.Errorf(colour.Sprintf("failed to fetch package index ^3%s^R from ^1%s^R: %s",
+			curl.Name, curl.Location, resp.Status))
+	}
+
+	if err := os.MkdirAll(paths.AppPaths.ReposDirectory, os.ModePerm); err != nil {
+		return err
+	}
+
+	// Build the new index next to the old one, then swap it in so a failed fetch never leaves the
+	// repository half-written
+	stagingDir, err := os.MkdirTemp(paths.AppPaths.ReposDirectory, "."+curl.Name+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	indexFile, err := os.CreateTemp(paths.AppPaths.ReposDirectory,
+		"."+curl.Name+"-index-*-"+filepath.Base(resp.Request.URL.Path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(indexFile.Name())
+	defer indexFile.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(indexFile, h), resp.Body); err != nil {
+		return err
+	}
+
+	// An index is either JSON or an archive of manifests, so there is nothing to mistake for one
+	format, err := extract.DetectFormat(indexFile.Name(), true)
+	if err != nil {
+		return err
+	}
+
+	if format != extract.Unknown {
+		err = curl.unpackArchive(indexFile.Name(), format, stagingDir)
+	} else {
+		err = curl.fetchIndex(indexFile.Name(), resp.Request.URL, stagingDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	newState := &indexState{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Revision:     hex.EncodeToString(h.Sum(nil)),
+		Updated:      time.Now(),
+	}
+	if err := curl.writeState(stagingDir, newState); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(curl.Path); err != nil {
+		return err
+	}
+	if err := os.Rename(stagingDir, curl.Path); err != nil {
+		return err
+	}
+
+	log.Infof("Updated packages repository %s, now at %s\n", curl.Name, newState.Revision)
+	return nil
+}
+
+// unpackArchive extracts an archive of manifests, flattening every .hcl file in it into outDir. It's
+// extracted next to outDir, so the files can be renamed into it without crossing filesystems.
+func (curl *CurlRepoLoader) unpackArchive(archive string, format extract.Format, outDir string) error {
+	tmpDir, err := os.MkdirTemp(filepath.Dir(outDir), "."+curl.Name+"-archive-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extract.Extract(archive, format, tmpDir, curl.Name, nil); err != nil {
+		return err
+	}
+
+	return filepath.Walk(tmpDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() || filepath.Ext(path) != ".hcl" {
+			return err
+		}
+
+		return os.Rename(path, filepath.Join(outDir, filepath.Base(path)))
+	})
+}
+
+// fetchIndex downloads every manifest listed in a JSON index into outDir
+func (curl *CurlRepoLoader) fetchIndex(indexPath string, indexURL *url.URL, outDir string) error {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return err
+	}
+
+	index := &HTTPIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return fmt.Errorf(colour.Sprintf("invalid package index at ^1%s^R: %s", curl.Location, err))
+	}
+
+	for _, manifest := range index.Manifests {
+		manifestURL, err := indexURL.Parse(manifest)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Base(manifestURL.Path)
+		if !strings.HasSuffix(name, ".hcl") {
+			log.Debugf("Skipping non-manifest %s in index %s", manifest, curl.Location)
+			continue
+		}
+
+		if err := downloadFile(manifestURL.String(), filepath.Join(outDir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func downloadFile(source string, dest string) error {
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := auth.Authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := download.NewClient(timeout).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf(colour.Sprintf("failed to download ^1%s^R: %s", source, resp.Status))
+	}
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
+
+func (curl *CurlRepoLoader) readState() (*indexState, error) {
+	data, err := os.ReadFile(filepath.Join(curl.Path, indexStateFile))
+	if err != nil {
+		return nil, err
+	}
+
+	state := &indexState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (curl *CurlRepoLoader) writeState(dir string, state *indexState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, indexStateFile), data, 0644)
+}
+
+func (curl *CurlRepoLoader) Remove() error {
+	return os.RemoveAll(curl.Path)
+}
+
+func (curl *CurlRepoLoader) HasPackage(name string) bool {
+	_, err := os.Stat(filepath.Join(curl.Path, name+".hcl"))
+	return err == nil
+}
+
+func (curl *CurlRepoLoader) ListPackages() ([]string, error) {
+	return listPackages(curl.Path)
+}
+
+// GetRevision returns the sha256 of the last fetched index
+func (curl *CurlRepoLoader) GetRevision() (string, error) {
+	state, err := curl.readState()
+	if err != nil {
+		return "", err
+	}
+
+	return state.Revision, nil
+}
+
+func (curl *CurlRepoLoader) GetName() string {
+	return curl.Name
+}
+
+func (curl *CurlRepoLoader) GetPath() string {
+	return curl.Path
+}
+
+func (curl *CurlRepoLoader) GetLocation() string {
+	return curl.Location
+}
@@ -0,0 +1,304 @@
+package repos
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josephschmitt/hvm/download"
+)
+
+// indexServer serves package indexes from files, honoring If-None-Match and If-Modified-Since
+type indexServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	files    map[string][]byte
+	etag     string
+	modified time.Time
+	requests map[string]int
+}
+
+func newIndexServer(t *testing.T, files map[string][]byte) *indexServer {
+	t.Helper()
+
+	server := &indexServer{files: files, requests: map[string]int{}}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func (server *indexServer) serve(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.requests[r.URL.Path]++
+
+	data, ok := server.files[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if server.etag != "" {
+		w.Header().Set("ETag", server.etag)
+		if r.Header.Get("If-None-Match") == server.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if !server.modified.IsZero() {
+		w.Header().Set("Last-Modified", server.modified.UTC().Format(http.TimeFormat))
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil &&
+			!server.modified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Write(data)
+}
+
+func (server *indexServer) set(path string, data []byte) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.files[path] = data
+}
+
+func (server *indexServer) count(path string) int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return server.requests[path]
+}
+
+func manifestsTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, body := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func readManifest(t *testing.T, loader RepoLoader, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(loader.GetPath(), name+".hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func listed(t *testing.T, loader RepoLoader) string {
+	t.Helper()
+
+	names, err := loader.ListPackages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Join(names, ",")
+}
+
+func TestCurlRepoLoaderJSONIndex(t *testing.T) {
+	usePaths(t)
+
+	server := newIndexServer(t, map[string][]byte{
+		"/repo/index.json":   []byte(`{"manifests": ["node.hcl", "tools/jq.hcl", "README.md"]}`),
+		"/repo/node.hcl":     []byte(`name = "node"`),
+		"/repo/tools/jq.hcl": []byte(`name = "jq"`),
+		"/repo/README.md":    []byte(`# Packages`),
+	})
+	server.etag = `"v1"`
+
+	loader := NewCurlRepoLoader("web", server.URL+"/repo/index.json")
+	if err := loader.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	if names := listed(t, loader); names != "jq,node" {
+		t.Errorf("expected jq and node, got %s", names)
+	}
+	if server.count("/repo/README.md") != 0 {
+		t.Error("expected files that aren't manifests to be skipped")
+	}
+
+	revision, err := loader.GetRevision()
+	if err != nil || revision == "" {
+		t.Fatalf("expected a revision, got %q, %v", revision, err)
+	}
+
+	// Nothing is downloaded again while the ETag matches
+	if err := loader.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.count("/repo/node.hcl"); n != 1 {
+		t.Errorf("expected an unchanged index not to be downloaded again, node.hcl fetched %d times",
+			n)
+	}
+	if again, _ := loader.GetRevision(); again != revision {
+		t.Errorf("revision changed from %s to %s without the index changing", revision, again)
+	}
+
+	server.set("/repo/index.json", []byte(`{"manifests": ["node.hcl"]}`))
+	server.set("/repo/node.hcl", []byte(`name = "node" # v2`))
+	server.etag = `"v2"`
+
+	if err := loader.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if names := listed(t, loader); names != "node" {
+		t.Errorf("expected manifests dropped from the index to be removed, got %s", names)
+	}
+	if manifest := readManifest(t, loader, "node"); !strings.Contains(manifest, "v2") {
+		t.Errorf("expected the updated manifest, got %q", manifest)
+	}
+	if again, _ := loader.GetRevision(); again == revision {
+		t.Error("expected the revision to change with the index")
+	}
+}
+
+func TestCurlRepoLoaderArchive(t *testing.T) {
+	usePaths(t)
+
+	server := newIndexServer(t, map[string][]byte{
+		"/repo.tar.gz": manifestsTarball(t, map[string]string{
+			"packages-main/node.hcl":     `name = "node"`,
+			"packages-main/tools/jq.hcl": `name = "jq"`,
+			"packages-main/README.md":    "# Packages",
+		}),
+	})
+	server.modified = time.Now().Add(-time.Hour)
+
+	loader := NewCurlRepoLoader("web", server.URL+"/repo.tar.gz")
+	if err := loader.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	if names := listed(t, loader); names != "jq,node" {
+		t.Errorf("expected jq and node, got %s", names)
+	}
+
+	// Not modified since it was fetched
+	if err := loader.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if names := listed(t, loader); names != "jq,node" {
+		t.Errorf("expected the repository to be kept when not modified, got %s", names)
+	}
+
+	server.set("/repo.tar.gz", manifestsTarball(t, map[string]string{"deno.hcl": `name = "deno"`}))
+	server.modified = time.Now()
+
+	if err := loader.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if names := listed(t, loader); names != "deno" {
+		t.Errorf("expected the new archive's manifests, got %s", names)
+	}
+}
+
+func TestCurlRepoLoaderErrors(t *testing.T) {
+	usePaths(t)
+
+	server := newIndexServer(t, map[string][]byte{
+		"/good/index.json":    []byte(`{"manifests": ["node.hcl"]}`),
+		"/good/node.hcl":      []byte(`name = "node"`),
+		"/invalid/index.json": []byte(`{"manifests": `),
+		"/partial/index.json": []byte(`{"manifests": ["node.hcl", "missing.hcl"]}`),
+		"/partial/node.hcl":   []byte(`name = "node"`),
+	})
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	for name, location := range map[string]string{
+		"not found":        server.URL + "/missing/index.json",
+		"server error":     broken.URL + "/index.json",
+		"invalid index":    server.URL + "/invalid/index.json",
+		"missing manifest": server.URL + "/partial/index.json",
+	} {
+		loader := NewCurlRepoLoader("web", location)
+		if err := loader.Get(); err == nil {
+			t.Errorf("%s: expected fetching %s to fail", name, location)
+		}
+		if _, err := os.Stat(loader.GetPath()); !os.IsNotExist(err) {
+			t.Errorf("%s: expected nothing to be left at %s", name, loader.GetPath())
+		}
+	}
+
+	// A failed update keeps the manifests fetched before
+	loader := NewCurlRepoLoader("web", server.URL+"/good/index.json")
+	if err := loader.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	server.set("/good/index.json", []byte(`{"manifests": ["node.hcl", "missing.hcl"]}`))
+	if err := loader.Update(); err == nil {
+		t.Error("expected an update with a missing manifest to fail")
+	}
+	if names := listed(t, loader); names != "node" {
+		t.Errorf("expected the manifests from before the failed update, got %s", names)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(loader.GetPath()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected temporary files to be cleaned up, found %d entries", len(entries))
+	}
+}
+
+func TestCurlRepoLoaderTimeout(t *testing.T) {
+	usePaths(t)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	SetTimeout(100 * time.Millisecond)
+	defer SetTimeout(download.DefaultTimeout)
+
+	start := time.Now()
+	if err := NewCurlRepoLoader("web", server.URL+"/index.json").Get(); err == nil {
+		t.Error("expected a server that never responds to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the fetch to give up after the timeout, took %s", elapsed)
+	}
+}
@@ -0,0 +1,161 @@
+package repos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/offline"
+	"github.com/josephschmitt/hvm/paths"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultUpdateInterval is how long a repository is considered fresh after it was last updated
+const DefaultUpdateInterval = 24 * time.Hour
+
+const updatesFile = "repos.json"
+
+// updates records when each repository was last successfully updated, by name
+type updates map[string]time.Time
+
+func updatesPath() string {
+	return filepath.Join(paths.AppPaths.ConfigDirectory, updatesFile)
+}
+
+func loadUpdates() updates {
+	recorded := make(updates)
+
+	if data, err := os.ReadFile(updatesPath()); err == nil {
+		if err := json.Unmarshal(data, &recorded); err != nil {
+			log.Debugf("Ignoring unreadable repository update times: %s", err)
+		}
+	}
+
+	return recorded
+}
+
+func (recorded updates) save() error {
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(updatesPath()), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(updatesPath(), data, 0644)
+}
+
+// LastUpdated returns when the repository was last successfully updated, or the zero time if it
+// never was
+func LastUpdated(loader RepoLoader) time.Time {
+	return loadUpdates()[loader.GetName()]
+}
+
+// State returns a fingerprint of the configured repositories and when each was last updated. It
+// changes whenever a repository is added, removed, reconfigured or updated, so anything derived
+// from the repositories' manifests can tell when it's out of date.
+func State() string {
+	recorded := loadUpdates()
+
+	h := sha256.New()
+	for _, repo := range Repositories() {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%d\x00%d\n", repo.Name, repo.Type, repo.URL, repo.Ref,
+			repo.Priority, recorded[repo.Name].UnixNano())
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get fetches a repository and records the time it was fetched at. Offline, only repositories that
+// were already fetched are available.
+func Get(loader RepoLoader) error {
+	if offline.Enabled() {
+		if _, err := os.Stat(loader.GetPath()); err != nil {
+			return &offline.Error{What: "repository " + loader.GetName(), Source: loader.GetLocation()}
+		}
+
+		return nil
+	}
+
+	if err := loader.Get(); err != nil {
+		return err
+	}
+
+	recordUpdate(loader.GetName())
+	return nil
+}
+
+// Update updates a repository and records the time it was updated at. Offline, this does nothing.
+func Update(loader RepoLoader) error {
+	if offline.Enabled() {
+		log.Infof(colour.Sprintf("Offline, not updating repository ^3%s^R\n", loader.GetName()))
+		return nil
+	}
+
+	if err := loader.Update(); err != nil {
+		return err
+	}
+
+	recordUpdate(loader.GetName())
+	return nil
+}
+
+func recordUpdate(name string) {
+	recorded := loadUpdates()
+	recorded[name] = time.Now()
+	if err := recorded.save(); err != nil {
+		log.Debugf("Unable to save repository update times: %s", err)
+	}
+}
+
+// Forget clears the recorded update time of a repository that has been removed
+func Forget(name string) error {
+	recorded := loadUpdates()
+	if _, ok := recorded[name]; !ok {
+		return nil
+	}
+
+	delete(recorded, name)
+	return recorded.save()
+}
+
+// UpdateStale updates every repository that hasn't been updated within interval. If a repository
+// can't be updated, e.g. because the network is unavailable, its existing copy is used instead.
+func UpdateStale(interval time.Duration) error {
+	if offline.Enabled() {
+		log.Debugf("Offline, using cached repositories\n")
+		return nil
+	}
+
+	recorded := loadUpdates()
+
+	for _, loader := range Loaders() {
+		_, statErr := os.Stat(loader.GetPath())
+		if statErr == nil && time.Since(recorded[loader.GetName()]) < interval {
+			log.Debugf("Repository %s is up-to-date, last updated %s\n", loader.GetName(),
+				recorded[loader.GetName()].Format(time.RFC3339))
+			continue
+		}
+
+		err := Update(loader)
+		if err == nil {
+			continue
+		}
+
+		if statErr != nil {
+			return err
+		}
+
+		log.Warnf(colour.Sprintf("Unable to update repository ^3%s^R, using cached copy at ^6%s^R: %s",
+			loader.GetName(), loader.GetPath(), err))
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package repos
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/josephschmitt/hvm/offline"
+	"github.com/josephschmitt/hvm/paths"
+)
+
+// useWebRepository configures an http repository served by server, alongside a local default
+// repository so nothing is cloned from the network
+func useWebRepository(t *testing.T, server *indexServer) RepoLoader {
+	t.Helper()
+
+	os.Unsetenv(RepoPathEnv)
+	useRepositories(t,
+		&Repository{Name: "web", Type: HTTPRepository, URL: server.URL + "/index.json"},
+		&Repository{Name: paths.PackageRepository, Type: LocalRepository, URL: localRepo(t)},
+	)
+
+	return NewCurlRepoLoader("web", server.URL+"/index.json")
+}
+
+func TestUpdateStale(t *testing.T) {
+	usePaths(t)
+
+	server := newIndexServer(t, map[string][]byte{
+		"/index.json": []byte(`{"manifests": ["node.hcl"]}`),
+		"/node.hcl":   []byte(`name = "node"`),
+	})
+	loader := useWebRepository(t, server)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		requests int
+	}{
+		{"never fetched", time.Hour, 1},
+		{"fresh", time.Hour, 1},
+		{"stale", 0, 2},
+	}
+
+	for _, test := range tests {
+		if err := UpdateStale(test.interval); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if n := server.count("/index.json"); n != test.requests {
+			t.Errorf("%s: expected %d requests for the index, got %d", test.name, test.requests, n)
+		}
+	}
+
+	if LastUpdated(loader).IsZero() {
+		t.Error("expected the update time to be recorded")
+	}
+}
+
+func TestUpdateStaleUnavailable(t *testing.T) {
+	usePaths(t)
+
+	server := newIndexServer(t, map[string][]byte{
+		"/index.json": []byte(`{"manifests": ["node.hcl"]}`),
+		"/node.hcl":   []byte(`name = "node"`),
+	})
+	loader := useWebRepository(t, server)
+
+	if err := UpdateStale(0); err != nil {
+		t.Fatal(err)
+	}
+	updated := LastUpdated(loader)
+
+	// The cached copy is used when the repository can't be updated
+	server.set("/index.json", []byte(`{"manifests": ["node.hcl", "missing.hcl"]}`))
+	if err := UpdateStale(0); err != nil {
+		t.Errorf("expected the cached copy to be used, got %s", err)
+	}
+	if !loader.HasPackage("node") {
+		t.Error("expected the cached copy to be kept")
+	}
+	if !LastUpdated(loader).Equal(updated) {
+		t.Error("expected a failed update not to be recorded")
+	}
+
+	// Without a cached copy there's nothing to fall back to
+	if err := loader.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateStale(0); err == nil {
+		t.Error("expected a repository that was never fetched to fail")
+	}
+}
+
+func TestUpdateStaleOffline(t *testing.T) {
+	usePaths(t)
+	offline.Enable(true)
+	defer offline.Enable(false)
+
+	server := newIndexServer(t, map[string][]byte{"/index.json": []byte(`{"manifests": []}`)})
+	loader := useWebRepository(t, server)
+
+	if err := UpdateStale(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := Update(loader); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.count("/index.json"); n != 0 {
+		t.Errorf("expected no requests offline, got %d", n)
+	}
+
+	if err := Get(loader); !offline.IsError(err) {
+		t.Errorf("expected getting a repository that was never fetched to fail offline, got %v",
+			err)
+	}
+}
+
+func TestForget(t *testing.T) {
+	usePaths(t)
+
+	server := newIndexServer(t, map[string][]byte{"/index.json": []byte(`{"manifests": []}`)})
+	loader := useWebRepository(t, server)
+
+	if err := Forget("web"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Get(loader); err != nil {
+		t.Fatal(err)
+	}
+	before := State()
+
+	if err := Forget("web"); err != nil {
+		t.Fatal(err)
+	}
+	if !LastUpdated(loader).IsZero() {
+		t.Error("expected the update time to be forgotten")
+	}
+	if State() == before {
+		t.Error("expected forgetting a repository to change the state")
+	}
+}
@@ -0,0 +1,105 @@
+package repos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/paths"
+	log "github.com/sirupsen/logrus"
+)
+
+// RepoPathEnv points at a local directory of manifests that's searched before any other repository
+const RepoPathEnv = "HVM_REPO_PATH"
+
+// LocalRepoName is the name of the repository created from RepoPathEnv
+const LocalRepoName = "local"
+
+// LocalRepoLoader reads manifests straight from a directory on disk, without copying them anywhere.
+// It's meant for package authors iterating on manifests before publishing them.
+type LocalRepoLoader struct {
+	Name     string
+	Location string
+}
+
+func NewLocalRepoLoader(name string, dir string) RepoLoader {
+	if abs, err := filepath.Abs(paths.AppPaths.ResolveDir(dir)); err == nil {
+		dir = abs
+	}
+
+	return &LocalRepoLoader{
+		Name:     name,
+		Location: dir,
+	}
+}
+
+// Get only checks that the directory exists, since there is nothing to fetch
+func (l *LocalRepoLoader) Get() error {
+	info, err := os.Stat(l.Location)
+	if err != nil {
+		return fmt.Errorf(colour.Sprintf("local package repository ^3%s^R not found at ^1%s^R",
+			l.Name, l.Location))
+	} else if !info.IsDir() {
+		return fmt.Errorf(colour.Sprintf("local package repository ^3%s^R at ^1%s^R is not a "+
+			"directory", l.Name, l.Location))
+	}
+
+	return nil
+}
+
+func (l *LocalRepoLoader) Update() error {
+	log.Debugf("Repository %s is a local directory, nothing to update\n", l.Name)
+	return l.Get()
+}
+
+// Remove is a no-op, the directory belongs to the user rather than hvm
+func (l *LocalRepoLoader) Remove() error {
+	return nil
+}
+
+func (l *LocalRepoLoader) HasPackage(name string) bool {
+	_, err := os.Stat(filepath.Join(l.Location, name+".hcl"))
+	return err == nil
+}
+
+func (l *LocalRepoLoader) ListPackages() ([]string, error) {
+	return listPackages(l.Location)
+}
+
+// GetRevision returns a sha256 over the contents of every manifest in the directory
+func (l *LocalRepoLoader) GetRevision() (string, error) {
+	files, err := filepath.Glob(filepath.Join(l.Location, "*.hcl"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		h.Write([]byte(filepath.Base(file)))
+		h.Write(data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (l *LocalRepoLoader) GetName() string {
+	return l.Name
+}
+
+func (l *LocalRepoLoader) GetPath() string {
+	return l.Location
+}
+
+func (l *LocalRepoLoader) GetLocation() string {
+	return l.Location
+}
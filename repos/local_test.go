@@ -0,0 +1,139 @@
+package repos
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/josephschmitt/hvm/paths"
+)
+
+func TestNewLocalRepoLoader(t *testing.T) {
+	usePaths(t)
+
+	tests := []struct {
+		dir      string
+		expected string
+	}{
+		{"/srv/manifests", "/srv/manifests"},
+		{"~/manifests", filepath.Join(paths.AppPaths.HomeDirectory, "manifests")},
+		{"$HOME/manifests", filepath.Join(paths.AppPaths.HomeDirectory, "manifests")},
+		{"${HOME}/manifests", filepath.Join(paths.AppPaths.HomeDirectory, "manifests")},
+	}
+
+	for _, test := range tests {
+		if actual := NewLocalRepoLoader("dev", test.dir).GetPath(); actual != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.dir, test.expected, actual)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := NewLocalRepoLoader("dev", "manifests").GetPath(); actual !=
+		filepath.Join(wd, "manifests") {
+		t.Errorf("expected a relative directory to be made absolute, got %s", actual)
+	}
+}
+
+func TestLocalRepoLoader(t *testing.T) {
+	usePaths(t)
+
+	dir := localRepo(t, "node", "jq")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Packages"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLocalRepoLoader("dev", dir)
+	if err := loader.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := loader.ListPackages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := strings.Join(names, ","); actual != "jq,node" {
+		t.Errorf("expected jq and node, got %s", actual)
+	}
+
+	for name, expected := range map[string]bool{"node": true, "jq": true, "deno": false,
+		"README": false} {
+		if actual := loader.HasPackage(name); actual != expected {
+			t.Errorf("%s: expected HasPackage to be %t", name, expected)
+		}
+	}
+
+	revision, err := loader.GetRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := loader.GetRevision(); again != revision {
+		t.Error("expected the revision to be stable while the manifests are unchanged")
+	}
+
+	// Edits show up straight away, without updating the repository
+	if err := os.WriteFile(filepath.Join(dir, "node.hcl"), []byte(`name = "node" # edited`),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	if edited, _ := loader.GetRevision(); edited == revision {
+		t.Error("expected editing a manifest to change the revision")
+	}
+
+	// The directory belongs to the user, so removing the repository leaves it alone
+	if err := loader.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "node.hcl")); err != nil {
+		t.Errorf("expected removing the repository to keep its manifests: %s", err)
+	}
+}
+
+func TestLocalRepoLoaderGet(t *testing.T) {
+	usePaths(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "node.hcl")
+	if err := os.WriteFile(file, []byte(`name = "node"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir string
+		ok  bool
+	}{
+		{dir, true},
+		{filepath.Join(dir, "missing"), false},
+		{file, false},
+	}
+
+	for _, test := range tests {
+		err := NewLocalRepoLoader("dev", test.dir).Get()
+		if test.ok != (err == nil) {
+			t.Errorf("%s: expected ok=%t, got error %v", test.dir, test.ok, err)
+		}
+	}
+}
+
+func TestFindPackageRepoPath(t *testing.T) {
+	usePaths(t)
+	useRepositories(t, &Repository{Name: "team", Type: LocalRepository, URL: localRepo(t, "node")})
+
+	os.Setenv(RepoPathEnv, localRepo(t, "node"))
+	defer os.Unsetenv(RepoPathEnv)
+
+	loader, err := FindPackage("node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loader.GetName() != LocalRepoName {
+		t.Errorf("expected %s to shadow the configured repositories, found node in %s", RepoPathEnv,
+			loader.GetName())
+	}
+}
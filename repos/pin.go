@@ -0,0 +1,153 @@
+package repos
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/alecthomas/colour"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	log "github.com/sirupsen/logrus"
+)
+
+var commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// updatePin fetches the repository and checks out its pin again, so that a pinned branch moves to
+// its latest commit. Reports when the pin differs from the upstream HEAD.
+func (g *GitRepoLoader) updatePin(repo *git.Repository) error {
+	w := log.New().WriterLevel(log.DebugLevel)
+	defer w.Close()
+
+	before, _ := repo.Head()
+
+	if err := g.fetch(repo); err != nil {
+		return err
+	}
+
+	hash, err := g.checkoutPin(repo)
+	if err != nil {
+		return err
+	}
+
+	if before != nil && before.Hash() == hash {
+		log.Infof("Repository %s already up-to-date, pinned to %s at %s\n", g.Name, g.Pin, hash)
+	} else {
+		log.Infof("Updated packages repository %s, pinned to %s at %s\n", g.Name, g.Pin, hash)
+	}
+
+	if upstream, err := g.upstreamHead(repo); err != nil {
+		log.Debugf("Unable to determine upstream HEAD of %s: %s", g.Name, err)
+	} else if upstream != hash {
+		log.Warnf(colour.Sprintf("Repository ^3%s^R is pinned to ^5%s^R (^6%s^R), upstream HEAD is "+
+			"at ^6%s^R", g.Name, g.Pin, shortHash(hash), shortHash(upstream)))
+	}
+
+	return nil
+}
+
+func (g *GitRepoLoader) fetch(repo *git.Repository) error {
+	w := log.New().WriterLevel(log.DebugLevel)
+	defer w.Close()
+
+	err := repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		Tags:       git.AllTags,
+		Progress:   w,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+
+	return err
+}
+
+// checkoutPin checks out the commit the pin refers to as a detached HEAD, fetching it if it isn't
+// known locally yet
+func (g *GitRepoLoader) checkoutPin(repo *git.Repository) (plumbing.Hash, error) {
+	hash, err := g.resolvePin(repo)
+	if err != nil {
+		if err := g.fetch(repo); err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		if hash, err = g.resolvePin(repo); err != nil {
+			return plumbing.ZeroHash, fmt.Errorf(colour.Sprintf("unable to find ref ^1%s^R in "+
+				"repository ^3%s^R at ^6%s^R", g.Pin, g.Name, g.Location))
+		}
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	head, err := repo.Head()
+	if err == nil && head.Hash() == hash {
+		return hash, nil
+	}
+
+	log.Debugf("Check out %s of repo %s at %s\n", g.Pin, g.Name, hash)
+
+	return hash, worktree.Checkout(&git.CheckoutOptions{Hash: hash, Force: true})
+}
+
+// resolvePin finds the commit of the pin, trying it as a branch, then a tag, then a full ref or
+// commit hash
+func (g *GitRepoLoader) resolvePin(repo *git.Repository) (plumbing.Hash, error) {
+	candidates := []plumbing.Revision{
+		plumbing.Revision(plumbing.NewRemoteReferenceName("origin", g.Pin)),
+		plumbing.Revision(plumbing.NewTagReferenceName(g.Pin)),
+		plumbing.Revision(g.Pin),
+	}
+
+	for _, candidate := range candidates {
+		hash, err := repo.ResolveRevision(candidate)
+		if err == nil {
+			return *hash, nil
+		}
+	}
+
+	// ResolveRevision only expands full hashes, so look abbreviated ones up directly
+	if commitPattern.MatchString(g.Pin) {
+		if commit, err := repo.CommitObject(plumbing.NewHash(g.Pin)); err == nil {
+			return commit.Hash, nil
+		}
+	}
+
+	return plumbing.ZeroHash, plumbing.ErrReferenceNotFound
+}
+
+// upstreamHead asks the remote which commit its HEAD points at
+func (g *GitRepoLoader) upstreamHead(repo *git.Repository) (plumbing.Hash, error) {
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	refs, err := remote.List(&git.ListOptions{})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference)
+	for _, ref := range refs {
+		byName[ref.Name()] = ref
+	}
+
+	head := byName[plumbing.HEAD]
+	for head != nil && head.Type() == plumbing.SymbolicReference {
+		head = byName[head.Target()]
+	}
+
+	if head == nil {
+		return plumbing.ZeroHash, plumbing.ErrReferenceNotFound
+	}
+
+	return head.Hash(), nil
+}
+
+func shortHash(hash plumbing.Hash) string {
+	return hash.String()[:7]
+}
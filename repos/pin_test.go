@@ -0,0 +1,144 @@
+package repos
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// upstream is a git repository of manifests for loaders to clone from
+type upstream struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+}
+
+func newUpstream(t *testing.T) *upstream {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &upstream{t: t, dir: dir, repo: repo}
+}
+
+// commit writes manifests and commits them, returning the new commit
+func (u *upstream) commit(manifests map[string]string) plumbing.Hash {
+	u.t.Helper()
+
+	worktree, err := u.repo.Worktree()
+	if err != nil {
+		u.t.Fatal(err)
+	}
+
+	for name, manifest := range manifests {
+		if err := os.WriteFile(filepath.Join(u.dir, name+".hcl"), []byte(manifest), 0644); err != nil {
+			u.t.Fatal(err)
+		}
+		if _, err := worktree.Add(name + ".hcl"); err != nil {
+			u.t.Fatal(err)
+		}
+	}
+
+	hash, err := worktree.Commit("Update manifests", &git.CommitOptions{
+		Author: &object.Signature{Name: "hvm", Email: "hvm@example.com", When: time.Now()},
+	})
+	if err != nil {
+		u.t.Fatal(err)
+	}
+
+	return hash
+}
+
+func (u *upstream) ref(name plumbing.ReferenceName, hash plumbing.Hash) {
+	u.t.Helper()
+
+	if err := u.repo.Storer.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+		u.t.Fatal(err)
+	}
+}
+
+func TestGitRepoLoaderPin(t *testing.T) {
+	usePaths(t)
+
+	origin := newUpstream(t)
+	first := origin.commit(map[string]string{"node": `name = "node"`})
+	origin.ref(plumbing.NewTagReferenceName("v1.0.0"), first)
+	origin.ref(plumbing.NewBranchReferenceName("stable"), first)
+	latest := origin.commit(map[string]string{"jq": `name = "jq"`})
+
+	tests := []struct {
+		pin      string
+		expected plumbing.Hash
+		packages string
+	}{
+		{"", latest, "jq,node"},
+		{"v1.0.0", first, "node"},
+		{"stable", first, "node"},
+		{first.String(), first, "node"},
+		{first.String()[:7], first, "node"},
+		{"missing", plumbing.ZeroHash, ""},
+	}
+
+	for _, test := range tests {
+		loader := NewGitRepoLoader("pinned", origin.dir, test.pin)
+		err := loader.Get()
+		if test.expected.IsZero() {
+			if err == nil {
+				t.Errorf("%s: expected an unknown pin to fail", test.pin)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %s", test.pin, err)
+		} else if revision, _ := loader.GetRevision(); revision != test.expected.String() {
+			t.Errorf("%s: expected %s to be checked out, got %s", test.pin, test.expected, revision)
+		} else if names, _ := loader.ListPackages(); strings.Join(names, ",") != test.packages {
+			t.Errorf("%s: expected packages %s, got %s", test.pin, test.packages, names)
+		}
+
+		if err := loader.Remove(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGitRepoLoaderUpdatePin(t *testing.T) {
+	usePaths(t)
+
+	origin := newUpstream(t)
+	first := origin.commit(map[string]string{"node": `name = "node"`})
+	origin.ref(plumbing.NewBranchReferenceName("stable"), first)
+
+	loader := NewGitRepoLoader("pinned", origin.dir, "stable")
+	if err := loader.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A pinned branch follows its latest commit on update
+	latest := origin.commit(map[string]string{"jq": `name = "jq"`})
+	origin.ref(plumbing.NewBranchReferenceName("stable"), latest)
+	if err := loader.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if revision, _ := loader.GetRevision(); revision != latest.String() {
+		t.Errorf("expected the pinned branch to move to %s, got %s", latest, revision)
+	}
+
+	// A tag that didn't exist when the repository was cloned is fetched
+	origin.ref(plumbing.NewTagReferenceName("v1.0.0"), first)
+	loader.(*GitRepoLoader).Pin = "v1.0.0"
+	if err := loader.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if revision, _ := loader.GetRevision(); revision != first.String() {
+		t.Errorf("expected the new tag at %s to be checked out, got %s", first, revision)
+	}
+}
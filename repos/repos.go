@@ -3,6 +3,7 @@ package repos
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -17,6 +18,9 @@ type RepoLoader interface {
 	Update() error
 	Remove() error
 	HasPackage(name string) bool
+	ListPackages() ([]string, error)
+	GetRevision() (string, error)
+	GetName() string
 	GetPath() string
 	GetLocation() string
 }
@@ -26,20 +30,31 @@ type GitRepoLoader struct {
 	Location string
 	Path     string
 	Ref      plumbing.ReferenceName
+
+	// Pin is a tag, branch or commit to check out exactly, instead of pulling the tip of Ref
+	Pin string
 }
 
-func NewGitRepoLoader(name string, url string) RepoLoader {
+// NewGitRepoLoader creates a loader for the git repository at url, cloned into its own directory
+// under ReposDirectory. An empty url refers to the default hvm-packages repository. If ref is set,
+// the repository is pinned to that tag, branch or commit.
+func NewGitRepoLoader(name string, url string, ref string) RepoLoader {
 	loader := &GitRepoLoader{
+		Name:     name,
 		Location: url,
-		Path:     filepath.Join(paths.AppPaths.ReposDirectory),
+		Pin:      ref,
 	}
 
 	if loader.Location == "" {
 		loader.Name = paths.PackageRepository
 		loader.Location = DefaultRepository
-		loader.Ref = "refs/heads/main"
+		if loader.Pin == "" {
+			loader.Ref = "refs/heads/main"
+		}
 	}
 
+	loader.Path = filepath.Join(paths.AppPaths.ReposDirectory, loader.Name)
+
 	return loader
 }
 
@@ -49,18 +64,31 @@ func (g *GitRepoLoader) Get() error {
 	w := log.New().WriterLevel(log.DebugLevel)
 	defer w.Close()
 
+	// Pinned repositories need every branch and tag around to check out the pin
 	_, err := git.PlainClone(g.Path, false, &git.CloneOptions{
 		URL:           g.Location,
 		Progress:      w,
-		SingleBranch:  true,
+		SingleBranch:  g.Pin == "",
 		ReferenceName: g.Ref,
 	})
 
 	if err == git.ErrRepositoryAlreadyExists {
 		return g.Update()
+	} else if err != nil {
+		return err
 	}
 
-	return err
+	if g.Pin != "" {
+		repo, err := git.PlainOpen(g.Path)
+		if err != nil {
+			return err
+		}
+
+		_, err = g.checkoutPin(repo)
+		return err
+	}
+
+	return nil
 }
 
 func (g *GitRepoLoader) Update() error {
@@ -82,6 +110,10 @@ func (g *GitRepoLoader) Update() error {
 		return err
 	}
 
+	if g.Pin != "" {
+		return g.updatePin(repo)
+	}
+
 	worktree, err := repo.Worktree()
 	if err != nil {
 		return err
@@ -104,16 +136,16 @@ func (g *GitRepoLoader) Update() error {
 
 	hash := ref.Hash()
 	if alreadyUpToDate {
-		log.Infof("Repository already up-to-date, at %s\n", hash)
+		log.Infof("Repository %s already up-to-date, at %s\n", g.Name, hash)
 	} else {
-		log.Infof("Updated packages repository, now at %s\n", hash)
+		log.Infof("Updated packages repository %s, now at %s\n", g.Name, hash)
 	}
 
 	return nil
 }
 
 func (g *GitRepoLoader) Remove() error {
-	return nil
+	return os.RemoveAll(g.Path)
 }
 
 func (g *GitRepoLoader) HasPackage(name string) bool {
@@ -126,6 +158,29 @@ func (g *GitRepoLoader) HasPackage(name string) bool {
 	return false
 }
 
+func (g *GitRepoLoader) ListPackages() ([]string, error) {
+	return listPackages(g.Path)
+}
+
+// GetRevision returns the commit the local clone of the repository is checked out at
+func (g *GitRepoLoader) GetRevision() (string, error) {
+	repo, err := git.PlainOpen(g.Path)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return ref.Hash().String(), nil
+}
+
+func (g *GitRepoLoader) GetName() string {
+	return g.Name
+}
+
 func (g *GitRepoLoader) GetPath() string {
 	return g.Path
 }
@@ -134,10 +189,17 @@ func (g *GitRepoLoader) GetLocation() string {
 	return g.Location
 }
 
-type CurlRepoLoader struct {
-	Location string
-}
+// listPackages returns the names of every package manifest in dir
+func listPackages(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.hcl"))
+	if err != nil {
+		return nil, err
+	}
 
-func (curl *CurlRepoLoader) Get() error {
-	return nil
+	var names []string
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".hcl"))
+	}
+
+	return names, nil
 }
@@ -0,0 +1,235 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/offline"
+	"github.com/josephschmitt/hvm/paths"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	GitRepository   = "git"
+	HTTPRepository  = "http"
+	LocalRepository = "local"
+)
+
+// Repository is a package repository declared in config.hcl. Repositories with a higher priority
+// are searched for packages first. Git repositories may be pinned to a tag, branch or commit with
+// Ref, including the default hvm-packages repository, whose URL can be left out.
+type Repository struct {
+	Name     string `hcl:"name,label"`
+	Type     string `hcl:"type,optional"`
+	URL      string `hcl:"url,optional"`
+	Ref      string `hcl:"ref,optional"`
+	Priority int    `hcl:"priority,optional"`
+}
+
+// repoNamePattern limits repository names to what's safe to use as a directory name under
+// ReposDirectory
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Validate checks a repository declared in config.hcl. Names are used as directory names, so they
+// can't contain path separators or start with a dot. Only the default hvm-packages repository may
+// leave out its url.
+func (repo *Repository) Validate() error {
+	if !repoNamePattern.MatchString(repo.Name) {
+		return fmt.Errorf(colour.Sprintf("invalid repository name ^1%s^R, it must start with a "+
+			"letter or number followed by letters, numbers, dots, dashes or underscores", repo.Name))
+	}
+
+	if repo.URL == "" && repo.Name != paths.PackageRepository {
+		return fmt.Errorf(colour.Sprintf("repository ^3%s^R has no url", repo.Name))
+	}
+
+	return nil
+}
+
+var configured []*Repository
+
+// Configure sets the repositories declared in config.hcl, which are searched in addition to the
+// default hvm-packages repository
+func Configure(repositories []*Repository) {
+	configured = repositories
+}
+
+// Loaders returns a loader for every configured repository in the order they should be searched
+func Loaders() []RepoLoader {
+	var loaders []RepoLoader
+	for _, repo := range Repositories() {
+		loaders = append(loaders, NewRepoLoader(repo))
+	}
+
+	return loaders
+}
+
+// Repositories returns every configured repository in the order they should be searched: highest
+// priority first, then in the order they were declared, with the default repository last among
+// those of equal priority. A directory set in HVM_REPO_PATH is always searched first.
+func Repositories() []*Repository {
+	migrateLegacyRepository()
+
+	var repositories []*Repository
+	for _, repo := range configured {
+		if repo.Name != LocalRepoName || os.Getenv(RepoPathEnv) == "" {
+			repositories = append(repositories, repo)
+		}
+	}
+
+	if !hasRepository(repositories, paths.PackageRepository) {
+		repositories = append(repositories, &Repository{Name: paths.PackageRepository})
+	}
+
+	sort.SliceStable(repositories, func(i, j int) bool {
+		return repositories[i].Priority > repositories[j].Priority
+	})
+
+	if dir := os.Getenv(RepoPathEnv); dir != "" {
+		repositories = append([]*Repository{{
+			Name: LocalRepoName,
+			Type: LocalRepository,
+			URL:  dir,
+		}}, repositories...)
+	}
+
+	return repositories
+}
+
+// GetMissing fetches every repository that hasn't been fetched yet. Repositories that can't be
+// fetched, e.g. offline or without network access, are skipped with a warning, since the others
+// may still have the package.
+func GetMissing() {
+	for _, loader := range Loaders() {
+		getMissing(loader)
+	}
+}
+
+// getMissing fetches a repository if it hasn't been fetched yet, returning whether it's available
+func getMissing(loader RepoLoader) bool {
+	if _, err := os.Stat(loader.GetPath()); !os.IsNotExist(err) {
+		return true
+	}
+
+	if offline.Enabled() {
+		log.Warnf(colour.Sprintf("Repository ^3%s^R hasn't been fetched yet and isn't available "+
+			"offline", loader.GetName()))
+		return false
+	}
+
+	if err := Get(loader); err != nil {
+		log.Warnf(colour.Sprintf("Unable to fetch repository ^3%s^R, skipping it: %s",
+			loader.GetName(), err))
+		return false
+	}
+
+	return true
+}
+
+// NewRepoLoader creates the loader for a repository declared in config.hcl
+func NewRepoLoader(repo *Repository) RepoLoader {
+	if repo.Name == paths.PackageRepository && repo.URL == "" {
+		return NewGitRepoLoader("", "", repo.Ref)
+	}
+
+	switch repo.Type {
+	case HTTPRepository:
+		return NewCurlRepoLoader(repo.Name, repo.URL)
+	case LocalRepository:
+		return NewLocalRepoLoader(repo.Name, repo.URL)
+	case GitRepository, "":
+	default:
+		log.Warnf(colour.Sprintf("Unknown type ^1%s^R for repository ^3%s^R, assuming git",
+			repo.Type, repo.Name))
+	}
+
+	return NewGitRepoLoader(repo.Name, repo.URL, repo.Ref)
+}
+
+// FindPackage returns the loader of the first repository that has a manifest for the package. The
+// name may be prefixed with a repository name, as in "repo/pkg", to only search that repository.
+// Repositories that haven't been fetched yet are fetched as they're searched, so a package found
+// in a higher priority repository doesn't wait on (or fail because of) the ones after it.
+func FindPackage(name string) (RepoLoader, error) {
+	repoName, pkgName := SplitPackageName(name)
+
+	var searched []string
+	for _, loader := range Loaders() {
+		if repoName != "" && loader.GetName() != repoName {
+			continue
+		}
+
+		searched = append(searched, loader.GetLocation())
+		if !getMissing(loader) {
+			continue
+		}
+
+		if loader.HasPackage(pkgName) {
+			return loader, nil
+		}
+	}
+
+	if repoName != "" && len(searched) == 0 {
+		return nil, fmt.Errorf(colour.Sprintf("no package repository named ^3%s^R", repoName))
+	}
+
+	return nil, fmt.Errorf(colour.Sprintf("no hvm-package found named \"^2%s^R\" in ^6%s^R\n"+
+		"Try updating your packages, or contributing a new one for \"^2%s^R\".", name,
+		strings.Join(searched, ", "), pkgName))
+}
+
+// SplitPackageName splits a "repo/pkg" package name into its repository and package parts. The
+// repository is empty if the name isn't prefixed with one.
+func SplitPackageName(name string) (string, string) {
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+
+	return "", name
+}
+
+// PackageName strips the repository prefix from a package name, if there is one
+func PackageName(name string) string {
+	_, pkgName := SplitPackageName(name)
+	return pkgName
+}
+
+func hasRepository(repositories []*Repository, name string) bool {
+	for _, repo := range repositories {
+		if repo.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// migrateLegacyRepository moves a clone of the default repository from where older versions of hvm
+// put it into its own directory under ReposDirectory
+func migrateLegacyRepository() {
+	legacyPath := filepath.Join(paths.AppPaths.ConfigDirectory, paths.PackageRepository)
+	newPath := filepath.Join(paths.AppPaths.ReposDirectory, paths.PackageRepository)
+
+	if _, err := os.Stat(filepath.Join(legacyPath, ".git")); err != nil {
+		return
+	}
+	if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+		return
+	}
+
+	if err := os.MkdirAll(paths.AppPaths.ReposDirectory, os.ModePerm); err != nil {
+		return
+	}
+
+	if err := os.Rename(legacyPath, newPath); err != nil {
+		log.Debugf("Unable to move package repository from %s to %s: %s", legacyPath, newPath, err)
+		return
+	}
+
+	log.Debugf(colour.Sprintf("Moved package repository to ^6%s^R\n", newPath))
+}
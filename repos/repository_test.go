@@ -0,0 +1,208 @@
+package repos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/josephschmitt/hvm/offline"
+	"github.com/josephschmitt/hvm/paths"
+)
+
+// usePaths points the repositories at a temporary home directory for the rest of the test
+func usePaths(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	original := paths.AppPaths
+	paths.AppPaths = paths.NewPathsFromHome(dir, filepath.Join(dir, "home"),
+		filepath.Join(dir, "tmp"))
+	t.Cleanup(func() { paths.AppPaths = original })
+}
+
+// useRepositories configures repositories for the rest of the test
+func useRepositories(t *testing.T, repositories ...*Repository) {
+	t.Helper()
+
+	Configure(repositories)
+	t.Cleanup(func() { Configure(nil) })
+}
+
+// localRepo creates a directory of manifests for the named packages
+func localRepo(t *testing.T, names ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range names {
+		manifest := "name = \"" + name + "\"\n"
+		if err := os.WriteFile(filepath.Join(dir, name+".hcl"), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestRepositoryValidate(t *testing.T) {
+	tests := []struct {
+		repo Repository
+		ok   bool
+	}{
+		{Repository{Name: "internal", URL: "https://example.com/repo.git"}, true},
+		{Repository{Name: "my_repo-1.0", URL: "https://example.com/repo.git"}, true},
+		{Repository{Name: paths.PackageRepository}, true},
+		{Repository{Name: paths.PackageRepository, Ref: "v1.0.0"}, true},
+		{Repository{Name: "internal"}, false},
+		{Repository{Name: "", URL: "https://example.com/repo.git"}, false},
+		{Repository{Name: ".hidden", URL: "https://example.com/repo.git"}, false},
+		{Repository{Name: "..", URL: "https://example.com/repo.git"}, false},
+		{Repository{Name: "a/b", URL: "https://example.com/repo.git"}, false},
+		{Repository{Name: `a\b`, URL: "https://example.com/repo.git"}, false},
+	}
+
+	for _, test := range tests {
+		err := test.repo.Validate()
+		if test.ok != (err == nil) {
+			t.Errorf("%+v: expected ok=%t, got error %v", test.repo, test.ok, err)
+		}
+	}
+}
+
+func TestRepositories(t *testing.T) {
+	usePaths(t)
+	os.Unsetenv(RepoPathEnv)
+
+	names := func() string {
+		var names []string
+		for _, repo := range Repositories() {
+			names = append(names, repo.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	useRepositories(t)
+	if actual := names(); actual != paths.PackageRepository {
+		t.Errorf("expected only the default repository, got %s", actual)
+	}
+
+	useRepositories(t,
+		&Repository{Name: "low", URL: "low", Priority: -1},
+		&Repository{Name: "first", URL: "first"},
+		&Repository{Name: "high", URL: "high", Priority: 10},
+		&Repository{Name: "second", URL: "second"},
+	)
+	expected := "high,first,second," + paths.PackageRepository + ",low"
+	if actual := names(); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	os.Setenv(RepoPathEnv, t.TempDir())
+	defer os.Unsetenv(RepoPathEnv)
+	if actual := names(); actual != LocalRepoName+","+expected {
+		t.Errorf("expected %s to be searched first, got %s", RepoPathEnv, actual)
+	}
+}
+
+func TestSplitPackageName(t *testing.T) {
+	tests := []struct {
+		name, repo, pkg string
+	}{
+		{"node", "", "node"},
+		{"internal/node", "internal", "node"},
+		{"internal/tools/node", "internal", "tools/node"},
+	}
+
+	for _, test := range tests {
+		repo, pkg := SplitPackageName(test.name)
+		if repo != test.repo || pkg != test.pkg {
+			t.Errorf("SplitPackageName(%q) = %q, %q, expected %q, %q", test.name, repo, pkg,
+				test.repo, test.pkg)
+		}
+		if PackageName(test.name) != test.pkg {
+			t.Errorf("PackageName(%q) = %q, expected %q", test.name, PackageName(test.name),
+				test.pkg)
+		}
+	}
+}
+
+func TestFindPackage(t *testing.T) {
+	usePaths(t)
+	os.Unsetenv(RepoPathEnv)
+
+	// A repository that can't be fetched, and counts how often that's tried
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	useRepositories(t,
+		&Repository{Name: "first", Type: LocalRepository, URL: localRepo(t, "tool"), Priority: 20},
+		&Repository{Name: "broken", Type: HTTPRepository, URL: server.URL, Priority: 10},
+		&Repository{Name: "missing", Type: LocalRepository, URL: "/no/such/repo", Priority: 5},
+		&Repository{Name: paths.PackageRepository, Type: LocalRepository,
+			URL: localRepo(t, "tool", "other")},
+	)
+
+	tests := []struct {
+		name     string
+		expected string
+		requests int32
+	}{
+		{"tool", "first", 0},
+		{"other", paths.PackageRepository, 1},
+		{"hvm-packages/tool", paths.PackageRepository, 1},
+		{"missing/other", "", 1},
+		{"nothing", "", 2},
+		{"unknown/tool", "", 2},
+	}
+
+	for _, test := range tests {
+		loader, err := FindPackage(test.name)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("FindPackage(%s): expected an error, found it in %s", test.name,
+					loader.GetName())
+			}
+		} else if err != nil {
+			t.Errorf("FindPackage(%s): %s", test.name, err)
+		} else if loader.GetName() != test.expected {
+			t.Errorf("FindPackage(%s) found it in %s, expected %s", test.name, loader.GetName(),
+				test.expected)
+		}
+
+		if n := atomic.LoadInt32(&requests); n != test.requests {
+			t.Errorf("FindPackage(%s): broken repository fetched %d time(s), expected %d",
+				test.name, n, test.requests)
+		}
+	}
+}
+
+func TestFindPackageOffline(t *testing.T) {
+	usePaths(t)
+	os.Unsetenv(RepoPathEnv)
+	offline.Enable(true)
+	defer offline.Enable(false)
+
+	useRepositories(t,
+		&Repository{Name: "internal", URL: "https://example.com/internal.git", Priority: 10},
+		&Repository{Name: paths.PackageRepository, Type: LocalRepository, URL: localRepo(t, "tool")},
+	)
+
+	loader, err := FindPackage("tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loader.GetName() != paths.PackageRepository {
+		t.Errorf("found tool in %s, expected %s", loader.GetName(), paths.PackageRepository)
+	}
+
+	if _, err := os.Stat(filepath.Join(paths.AppPaths.ReposDirectory, "internal")); err == nil {
+		t.Error("expected the internal repository not to be cloned offline")
+	}
+}
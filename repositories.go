@@ -0,0 +1,194 @@
+package hvm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/paths"
+	"github.com/josephschmitt/hvm/repos"
+	log "github.com/sirupsen/logrus"
+)
+
+type RepositoryInfo struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	URL      string    `json:"url"`
+	Ref      string    `json:"ref,omitempty"`
+	Priority int       `json:"priority"`
+	Revision string    `json:"revision,omitempty"`
+	Updated  time.Time `json:"updated,omitempty"`
+}
+
+// AddRepository fetches a new package repository, then saves it to the user-level config.hcl
+func AddRepository(ctx *context.Context, repo repos.Repository) error {
+	if err := repo.Validate(); err != nil {
+		return err
+	}
+
+	if repo.Name == paths.PackageRepository || repo.Name == repos.LocalRepoName {
+		return fmt.Errorf(colour.Sprintf("repository name ^3%s^R is reserved", repo.Name))
+	}
+
+	for _, existing := range repos.Repositories() {
+		if existing.Name == repo.Name {
+			return fmt.Errorf(colour.Sprintf("repository ^3%s^R already exists", repo.Name))
+		}
+	}
+
+	// The user config isn't tied to any project, so local paths can't be relative to one
+	if repo.Type == repos.LocalRepository {
+		dir, err := filepath.Abs(paths.AppPaths.ResolveDir(repo.URL))
+		if err != nil {
+			return err
+		}
+		repo.URL = dir
+	}
+
+	loader := repos.NewRepoLoader(&repo)
+
+	// Clean up after a failed add, but never remove a directory that was already there
+	_, statErr := os.Stat(loader.GetPath())
+	cleanup := func() {
+		if os.IsNotExist(statErr) {
+			loader.Remove()
+		}
+	}
+
+	if err := repos.Get(loader); err != nil {
+		cleanup()
+		return err
+	}
+
+	if err := context.AddRepository(repo); err != nil {
+		cleanup()
+		return err
+	}
+
+	log.Infof(colour.Sprintf("Added repository ^3%s^R at ^6%s^R", repo.Name, loader.GetLocation()))
+	return nil
+}
+
+// RemoveRepository removes a package repository from the user-level config.hcl, along with its
+// local copy
+func RemoveRepository(ctx *context.Context, name string) error {
+	var repo *repos.Repository
+	for _, existing := range repos.Repositories() {
+		if existing.Name == name {
+			repo = existing
+		}
+	}
+
+	if repo == nil {
+		return fmt.Errorf(colour.Sprintf("no package repository named ^3%s^R", name))
+	}
+
+	removed, err := context.RemoveRepository(name)
+	if err != nil {
+		return err
+	}
+
+	if !removed && name == paths.PackageRepository {
+		return fmt.Errorf(colour.Sprintf("the default repository ^3%s^R can't be removed", name))
+	} else if !removed {
+		return fmt.Errorf(colour.Sprintf("repository ^3%s^R isn't declared in ^6%s^R, remove it from "+
+			"the config.hcl that declares it instead", name, context.UserConfigPath()))
+	}
+
+	loader := repos.NewRepoLoader(repo)
+	if err := loader.Remove(); err != nil {
+		return err
+	}
+
+	if err := repos.Forget(name); err != nil {
+		log.Debugf("Unable to forget update time of repository %s: %s", name, err)
+	}
+
+	log.Infof(colour.Sprintf("Removed repository ^3%s^R", name))
+	return nil
+}
+
+// ListRepositories prints every configured package repository, in the order they're searched
+func ListRepositories(ctx *context.Context, asJSON bool) error {
+	var rows []*RepositoryInfo
+
+	for _, repo := range repos.Repositories() {
+		loader := repos.NewRepoLoader(repo)
+
+		info := &RepositoryInfo{
+			Name:     loader.GetName(),
+			Type:     repo.Type,
+			URL:      loader.GetLocation(),
+			Ref:      repo.Ref,
+			Priority: repo.Priority,
+			Updated:  repos.LastUpdated(loader),
+		}
+
+		if info.Type == "" {
+			info.Type = repos.GitRepository
+		}
+
+		if revision, err := loader.GetRevision(); err == nil {
+			info.Revision = revision
+		}
+
+		rows = append(rows, info)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "NAME\tTYPE\tURL\tREF\tREVISION\tUPDATED")
+	for _, repo := range rows {
+		revision := repo.Revision
+		if len(revision) > 12 {
+			revision = revision[:12]
+		}
+
+		updated := "never"
+		if !repo.Updated.IsZero() {
+			updated = repo.Updated.Local().Format("2006-01-02 15:04")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", repo.Name, repo.Type, repo.URL, repo.Ref,
+			revision, updated)
+	}
+
+	return nil
+}
+
+// UpdateRepositories updates the named package repositories, or all of them if none are named
+func UpdateRepositories(ctx *context.Context, names []string) error {
+	if len(names) == 0 {
+		return UpdatePackagesRepos(ctx)
+	}
+
+	loaders := make(map[string]repos.RepoLoader)
+	for _, loader := range repos.Loaders() {
+		loaders[loader.GetName()] = loader
+	}
+
+	for _, name := range names {
+		loader, ok := loaders[name]
+		if !ok {
+			return fmt.Errorf(colour.Sprintf("no package repository named ^3%s^R", name))
+		}
+
+		if err := repos.Update(loader); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package hvm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/paths"
+	"github.com/josephschmitt/hvm/repos"
+)
+
+func userRepositories(t *testing.T) []repos.Repository {
+	t.Helper()
+
+	if _, err := os.Stat(context.UserConfigPath()); os.IsNotExist(err) {
+		return nil
+	}
+
+	config, err := context.LoadConfig(context.UserConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config.Repositories
+}
+
+func TestAddRepository(t *testing.T) {
+	usePaths(t)
+	useRepo(t, nil)
+	ctx := &context.Context{}
+
+	tests := []struct {
+		name string
+		repo repos.Repository
+		ok   bool
+	}{
+		{"local directory", repos.Repository{Name: "team", Type: repos.LocalRepository,
+			URL: t.TempDir()}, true},
+		{"default repository", repos.Repository{Name: paths.PackageRepository,
+			Type: repos.LocalRepository, URL: t.TempDir()}, false},
+		{"local repository", repos.Repository{Name: repos.LocalRepoName,
+			Type: repos.LocalRepository, URL: t.TempDir()}, false},
+		{"invalid name", repos.Repository{Name: "../team", Type: repos.LocalRepository,
+			URL: t.TempDir()}, false},
+		{"missing directory", repos.Repository{Name: "missing", Type: repos.LocalRepository,
+			URL: filepath.Join(t.TempDir(), "missing")}, false},
+	}
+
+	for _, test := range tests {
+		err := AddRepository(ctx, test.repo)
+		if test.ok != (err == nil) {
+			t.Errorf("%s: expected ok=%t, got error %v", test.name, test.ok, err)
+		}
+	}
+
+	declared := userRepositories(t)
+	if len(declared) != 1 || declared[0].Name != "team" {
+		t.Errorf("expected only the team repository to be saved, got %+v", declared)
+	}
+}
+
+func TestRemoveRepository(t *testing.T) {
+	usePaths(t)
+	ctx := &context.Context{}
+
+	team := repos.Repository{Name: "team", Type: repos.LocalRepository, URL: t.TempDir()}
+	if err := context.AddRepository(team); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := useRepo(t, nil)
+	repos.Configure([]*repos.Repository{
+		&team,
+		{Name: "project", Type: repos.LocalRepository, URL: t.TempDir()},
+		{Name: paths.PackageRepository, Type: repos.LocalRepository, URL: dir},
+	})
+
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"missing", false},
+		{paths.PackageRepository, false},
+		{"project", false},
+		{"team", true},
+	}
+
+	for _, test := range tests {
+		err := RemoveRepository(ctx, test.name)
+		if test.ok != (err == nil) {
+			t.Errorf("%s: expected ok=%t, got error %v", test.name, test.ok, err)
+		}
+	}
+
+	if declared := userRepositories(t); len(declared) != 0 {
+		t.Errorf("expected the team repository to be removed, got %+v", declared)
+	}
+	if _, err := os.Stat(team.URL); err != nil {
+		t.Errorf("expected the local directory to be left alone: %s", err)
+	}
+}
@@ -0,0 +1,163 @@
+package hvm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/paths"
+	"github.com/josephschmitt/hvm/repos"
+	"github.com/josephschmitt/hvm/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// ResolutionTTL is how long a version range stays resolved to the same version before the known
+// versions of its package are looked up again. Adding, removing or updating a repository looks
+// them up again right away.
+const ResolutionTTL = 24 * time.Hour
+
+const resolutionCacheFile = "resolved.json"
+
+type resolution struct {
+	Version  string    `json:"version"`
+	Resolved time.Time `json:"resolved"`
+
+	// Repositories is the state of the package repositories the version was resolved against
+	Repositories string `json:"repositories"`
+}
+
+// resolutionCache remembers which exact version each version range last resolved to, so that
+// hvm run doesn't need to list versions (possibly over the network) on every invocation
+type resolutionCache map[string]*resolution
+
+func resolutionCachePath() string {
+	return filepath.Join(paths.AppPaths.ConfigDirectory, resolutionCacheFile)
+}
+
+func loadResolutionCache() resolutionCache {
+	cache := make(resolutionCache)
+
+	if data, err := os.ReadFile(resolutionCachePath()); err == nil {
+		if err := json.Unmarshal(data, &cache); err != nil {
+			log.Debugf("Ignoring unreadable version resolution cache: %s", err)
+		}
+	}
+
+	return cache
+}
+
+func (cache resolutionCache) save() error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolutionCachePath()), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(resolutionCachePath(), data, 0644)
+}
+
+// packageVersion determines which version of a package to use. The requested version takes
+// precedence over the version in the package's config block, and version ranges are resolved to
+// the highest known version that satisfies them, unless the version locked in hvm.lock does.
+// Returns the overrides from the package's config block, pinned to the resolved version.
+func packageVersion(
+	ctx *context.Context,
+	name string,
+	requested string,
+) (string, *manifest.PackageManifestOptions, error) {
+	overrides := ctx.Packages[name]
+	if requested == "" && overrides != nil {
+		requested = overrides.Version
+	}
+
+	version := lockedVersion(ctx, name, requested)
+	if version == "" {
+		var err error
+		if version, err = resolveVersion(name, requested); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if overrides != nil && overrides.Version != "" {
+		pinned := *overrides
+		pinned.Version = version
+		overrides = &pinned
+	}
+
+	return version, overrides, nil
+}
+
+// lockedVersion returns the version of a package locked in the project's hvm.lock, if the
+// requested version range is satisfied by it. Newer versions released since don't move the
+// project off of it.
+func lockedVersion(ctx *context.Context, name string, requested string) string {
+	if requested == "" || manifest.IsExactVersion(requested) {
+		return ""
+	}
+
+	pkg, _ := ctx.Lock.Get(repos.PackageName(name), manifest.Platform())
+	if pkg == nil {
+		return ""
+	}
+
+	if _, err := manifest.ResolveVersion(requested, []string{pkg.Version}); err != nil {
+		return ""
+	}
+
+	log.Debugf(colour.Sprintf("Using ^3%s@%s^R locked in ^6%s^R for ^3%s^R\n", name, pkg.Version,
+		ctx.Lock.GetPath(), requested))
+
+	return pkg.Version
+}
+
+// resolveVersion resolves a version range to an exact version. Exact versions are returned as-is.
+func resolveVersion(name string, constraint string) (string, error) {
+	if constraint == "" || manifest.IsExactVersion(constraint) {
+		return constraint, nil
+	}
+
+	cache := loadResolutionCache()
+	key := name + "@" + constraint
+	state := repos.State()
+	if cached := cache[key]; cached != nil && cached.Repositories == state &&
+		time.Since(cached.Resolved) < ResolutionTTL {
+		return cached.Version, nil
+	}
+
+	conf, err := manifest.NewPackageManfiestConfig(name)
+	if err != nil {
+		return "", err
+	}
+
+	versions, err := conf.KnownVersions()
+	if err != nil {
+		return "", err
+	}
+
+	installed, err := store.InstalledVersions(repos.PackageName(name))
+	if err != nil {
+		return "", err
+	}
+
+	version, err := manifest.ResolveVersion(constraint, append(versions, installed...))
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugf(colour.Sprintf("Resolved ^3%s@%s^R to ^2%s^R\n", name, constraint, version))
+
+	// Fetching missing repositories while finding the manifest changes their state
+	cache[key] = &resolution{Version: version, Resolved: time.Now(), Repositories: repos.State()}
+	if err := cache.save(); err != nil {
+		log.Debugf("Unable to save version resolution cache: %s", err)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,97 @@
+package hvm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/josephschmitt/hvm/paths"
+	"github.com/josephschmitt/hvm/repos"
+)
+
+func TestResolveVersion(t *testing.T) {
+	prefixed := strings.NewReplacer(`"1.`, `"v1.`, `"2.`, `"v2.`).Replace(toolManifest)
+
+	tests := []struct {
+		name       string
+		manifest   string
+		constraint string
+		installed  []string
+		expected   string
+		ok         bool
+	}{
+		{"no version", toolManifest, "", nil, "", true},
+		{"exact version", toolManifest, "1.0.0", nil, "1.0.0", true},
+		{"exact version with a v", toolManifest, "v1.0.0", nil, "v1.0.0", true},
+		{"caret range", toolManifest, "^1.0.0", nil, "1.2.0", true},
+		{"tilde range", toolManifest, "~1.1", nil, "1.1.0", true},
+		{"wildcard", toolManifest, "*", nil, "2.0.0", true},
+		{"installed version", toolManifest, "^1.0.0", []string{"1.5.0"}, "1.5.0", true},
+		{"nothing satisfies", toolManifest, "^3.0.0", nil, "", false},
+		{"versions with a v", prefixed, "^1.0.0", nil, "v1.2.0", true},
+		{"versions with a v and a range with one", prefixed, "^v1.0.0", nil, "v1.2.0", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usePaths(t)
+			useRepo(t, map[string]string{"tool": test.manifest})
+			installVersions(t, "tool", test.installed...)
+
+			version, err := resolveVersion("tool", test.constraint)
+			if test.ok != (err == nil) {
+				t.Fatalf("expected ok=%t, got error %v", test.ok, err)
+			}
+			if version != test.expected {
+				t.Errorf("resolved %q, expected %q", version, test.expected)
+			}
+		})
+	}
+}
+
+func TestResolveVersionCache(t *testing.T) {
+	usePaths(t)
+	dir := useRepo(t, map[string]string{"tool": toolManifest})
+
+	resolve := func(expected string) {
+		t.Helper()
+
+		version, err := resolveVersion("tool", "^1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != expected {
+			t.Errorf("resolved %s, expected %s", version, expected)
+		}
+	}
+
+	resolve("1.2.0")
+
+	// A new release isn't picked up until the cached resolution expires...
+	released := strings.Replace(toolManifest, `"2.0.0"`, `"1.3.0", "2.0.0"`, 1)
+	if err := os.WriteFile(filepath.Join(dir, "tool.hcl"), []byte(released), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resolve("1.2.0")
+
+	// ...or the repository is updated
+	if err := repos.Update(repos.Loaders()[0]); err != nil {
+		t.Fatal(err)
+	}
+	resolve("1.3.0")
+
+	// Adding or removing a repository resolves the range again too
+	released = strings.Replace(released, `"2.0.0"`, `"1.4.0", "2.0.0"`, 1)
+	if err := os.WriteFile(filepath.Join(dir, "tool.hcl"), []byte(released), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resolve("1.3.0")
+
+	other := t.TempDir()
+	repos.Configure([]*repos.Repository{
+		{Name: paths.PackageRepository, Type: repos.LocalRepository, URL: dir},
+		{Name: "other", Type: repos.LocalRepository, URL: other},
+	})
+	resolve("1.4.0")
+}
@@ -0,0 +1,39 @@
+package hvm
+
+import (
+	"strings"
+
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/repos"
+)
+
+// Search prints the available packages whose name or description contains term
+func Search(ctx *context.Context, term string, asJSON bool) error {
+	pkgs, err := SearchPackages(ctx, term)
+	if err != nil {
+		return err
+	}
+
+	return printList(pkgs, asJSON)
+}
+
+// SearchPackages finds the packages in every package repository whose name or description contains
+// term, ignoring case
+func SearchPackages(ctx *context.Context, term string) ([]*AvailablePackage, error) {
+	available, err := ListAvailablePackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	term = strings.ToLower(term)
+
+	pkgs := []*AvailablePackage{}
+	for _, pkg := range available {
+		if strings.Contains(strings.ToLower(repos.PackageName(pkg.Name)), term) ||
+			strings.Contains(strings.ToLower(pkg.Description), term) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	return pkgs, nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/colour"
+	"github.com/josephschmitt/hvm/paths"
+	log "github.com/sirupsen/logrus"
+)
+
+const locksDirectory = ".locks"
+
+// Lock is an inter-process lock held while a single package version is being installed
+type Lock struct {
+	file *os.File
+}
+
+// LockPackage blocks until it holds the install lock for the given package version. If another
+// process holds it, this waits for that process to finish.
+func LockPackage(name string, version string) (*Lock, error) {
+	lockPath := filepath.Join(paths.AppPaths.PkgsDirectory, locksDirectory, name+"@"+version+".lock")
+	if err := os.MkdirAll(filepath.Dir(lockPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	acquired, err := tryLockFile(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if !acquired {
+		log.Infof(colour.Sprintf("Waiting for another hvm process to finish installing ^3%s@%s^R...\n",
+			name, version))
+
+		if err := lockFile(file); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	return &Lock{file: file}, nil
+}
+
+func (l *Lock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	defer l.file.Close()
+	return unlockFile(l.file)
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josephschmitt/hvm/paths"
+)
+
+// usePaths points the store at a temporary home directory for the rest of the test
+func usePaths(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	original := paths.AppPaths
+	paths.AppPaths = paths.NewPathsFromHome(dir, filepath.Join(dir, "home"),
+		filepath.Join(dir, "tmp"))
+	t.Cleanup(func() { paths.AppPaths = original })
+}
+
+func TestLockPackageSerializesInstalls(t *testing.T) {
+	usePaths(t)
+
+	var active, maxActive, installs int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			lock, err := LockPackage("tool", "1.0.0")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer lock.Unlock()
+
+			n := atomic.AddInt32(&active, 1)
+			for {
+				max := atomic.LoadInt32(&maxActive)
+				if n <= max || atomic.CompareAndSwapInt32(&maxActive, max, n) {
+					break
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&installs, 1)
+			atomic.AddInt32(&active, -1)
+		}()
+	}
+	wg.Wait()
+
+	if installs != 8 {
+		t.Errorf("expected 8 installs, got %d", installs)
+	}
+	if maxActive != 1 {
+		t.Errorf("expected installs to run one at a time, got %d at once", maxActive)
+	}
+}
+
+func TestLockPackageVersionsAreIndependent(t *testing.T) {
+	usePaths(t)
+
+	lock, err := LockPackage("tool", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock()
+
+	acquired := make(chan error, 1)
+	go func() {
+		other, err := LockPackage("tool", "2.0.0")
+		if err == nil {
+			err = other.Unlock()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("locking another version waited on the held lock")
+	}
+}
+
+func TestLockPackageWaitsForUnlock(t *testing.T) {
+	usePaths(t)
+
+	lock, err := LockPackage("tool", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		other, err := LockPackage("tool", "1.0.0")
+		if err == nil {
+			err = other.Unlock()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired a lock that's already held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock wasn't acquired after it was released")
+	}
+}
+
+func TestNewStagingDirIsUnique(t *testing.T) {
+	usePaths(t)
+
+	seen := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		dir, err := NewStagingDir("tool", "1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if seen[dir] {
+			t.Fatalf("staging directory %s handed out twice", dir)
+		}
+		seen[dir] = true
+
+		if filepath.Dir(dir) != StagingDir() {
+			t.Errorf("staging directory %s isn't within %s", dir, StagingDir())
+		}
+	}
+}
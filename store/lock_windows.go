@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package store
+
+import "os"
+
+// File locking isn't supported on Windows yet, so installs there are not protected against
+// concurrent hvm processes
+
+func tryLockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
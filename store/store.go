@@ -0,0 +1,168 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/josephschmitt/hvm/paths"
+)
+
+// InstalledMarker is written into a package's output directory once it has been fully installed
+// and verified. Directories without it are considered incomplete.
+const InstalledMarker = ".hvm-installed"
+
+const stagingDirectory = ".staging"
+
+// Receipt is the content of the InstalledMarker file, describing where an installed package came
+// from
+type Receipt struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	Source    string    `json:"source"`
+	Checksum  string    `json:"checksum,omitempty"`
+	Installed time.Time `json:"installed"`
+}
+
+// StagingDir is the directory packages are installed into before being moved into place. It lives
+// under PkgsDirectory so the final rename stays on the same filesystem.
+func StagingDir() string {
+	return filepath.Join(paths.AppPaths.PkgsDirectory, stagingDirectory)
+}
+
+// NewStagingDir creates a fresh, uniquely named directory to install a package into
+func NewStagingDir(name string, version string) (string, error) {
+	if err := os.MkdirAll(StagingDir(), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp(StagingDir(), name+"-"+version+"-")
+	if err != nil {
+		return "", err
+	}
+
+	// MkdirTemp creates the directory as owner-only, which would carry over to the final install
+	return dir, os.Chmod(dir, 0755)
+}
+
+// IsInstalled reports whether outDir contains a completed install
+func IsInstalled(outDir string) bool {
+	_, err := os.Stat(filepath.Join(outDir, InstalledMarker))
+	return err == nil
+}
+
+func ReadReceipt(outDir string) (*Receipt, error) {
+	data, err := os.ReadFile(filepath.Join(outDir, InstalledMarker))
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &Receipt{}
+	if err := json.Unmarshal(data, receipt); err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+func WriteReceipt(dir string, receipt *Receipt) error {
+	data, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, InstalledMarker), data, 0644)
+}
+
+// Commit moves a verified staging directory into its final location, replacing whatever partial
+// install may already be there
+func Commit(stagingDir string, outDir string) error {
+	if err := os.MkdirAll(filepath.Dir(outDir), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(outDir); err != nil {
+		return err
+	}
+
+	return os.Rename(stagingDir, outDir)
+}
+
+// PackageDir returns the directory a package version is installed into
+func PackageDir(name string, version string) string {
+	return filepath.Join(paths.AppPaths.PkgsDirectory, name, version)
+}
+
+// InstalledPackages returns the names of every package with at least one directory in
+// PkgsDirectory, skipping hvm's own bookkeeping directories
+func InstalledPackages() ([]string, error) {
+	entries, err := os.ReadDir(paths.AppPaths.PkgsDirectory)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// InstalledVersions returns every completely installed version of a package
+func InstalledVersions(name string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(paths.AppPaths.PkgsDirectory, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() && IsInstalled(PackageDir(name, entry.Name())) {
+			versions = append(versions, entry.Name())
+		}
+	}
+
+	return versions, nil
+}
+
+// Remove deletes an installed package version, along with the package's directory if no other
+// versions are left in it
+func Remove(name string, version string) error {
+	if err := os.RemoveAll(PackageDir(name, version)); err != nil {
+		return err
+	}
+
+	pkgDir := filepath.Join(paths.AppPaths.PkgsDirectory, name)
+	if entries, err := os.ReadDir(pkgDir); err == nil && len(entries) == 0 {
+		return os.Remove(pkgDir)
+	}
+
+	return nil
+}
+
+// DirSize returns the total size in bytes of the regular files within dir
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
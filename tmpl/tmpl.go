@@ -2,6 +2,8 @@ package tmpl
 
 import (
 	_ "embed"
+	"regexp"
+	"strings"
 
 	"github.com/valyala/fasttemplate"
 )
@@ -21,3 +23,17 @@ func BuildRunScript(name string, bin string) string {
 		"bin":    bin,
 	})
 }
+
+var runScriptCommand = regexp.MustCompile(`^hvm run (\S+) --bin (\S+)`)
+
+// ParseRunScript extracts the package and bin names from the contents of a run script built by
+// BuildRunScript
+func ParseRunScript(script string) (name string, bin string, ok bool) {
+	for _, line := range strings.Split(script, "\n") {
+		if match := runScriptCommand.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			return match[1], match[2], true
+		}
+	}
+
+	return "", "", false
+}
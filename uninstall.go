@@ -0,0 +1,234 @@
+package hvm
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/colour"
+	"github.com/blang/semver/v4"
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/download"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/repos"
+	"github.com/josephschmitt/hvm/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// Uninstall removes installed package versions. Each spec must be in the form "name@version",
+// unless all is set, in which case every installed version of the named packages is removed.
+func Uninstall(ctx *context.Context, specs []string, all bool) error {
+	var reclaimed int64
+
+	for _, spec := range specs {
+		name, version := manifest.ParsePackageSpec(spec)
+		name = repos.PackageName(name)
+
+		if !isPathComponent(name) {
+			return fmt.Errorf(colour.Sprintf("invalid package name ^1%s^R", name))
+		}
+
+		installed, err := store.InstalledVersions(name)
+		if err != nil {
+			return err
+		}
+
+		// Only versions found in the store are removed, so a version can't point anywhere else
+		var versions []string
+		if version != "" {
+			if !isInstalledVersion(installed, version) {
+				log.Warnf(colour.Sprintf("^3%s@%s^R is not installed, skipping...", name, version))
+				continue
+			}
+			versions = []string{version}
+		} else if all {
+			versions = installed
+		} else {
+			return fmt.Errorf(colour.Sprintf("no version given for ^3%s^R, use ^5%s@<version>^R or "+
+				"--all to remove every version", name, name))
+		}
+
+		if len(versions) == 0 {
+			log.Warnf(colour.Sprintf("No installed versions of ^3%s^R found, skipping...", name))
+			continue
+		}
+
+		for _, version := range versions {
+			size, err := removePackage(name, version)
+			if os.IsNotExist(err) {
+				log.Warnf(colour.Sprintf("^3%s@%s^R is not installed, skipping...", name, version))
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			reclaimed += size
+			colour.Printf("^1Removed^R ^3%s@%s^R (%s)\n", name, version, download.FormatBytes(size))
+		}
+	}
+
+	if reclaimed > 0 {
+		colour.Printf("Reclaimed ^2%s^R\n", download.FormatBytes(reclaimed))
+	}
+
+	return nil
+}
+
+// GC removes every installed package version that isn't referenced by any config.hcl hvm has
+// loaded, or by its package manifest as the default version
+func GC(ctx *context.Context, dryRun bool) error {
+	registry, err := context.LoadRegistry()
+	if err != nil {
+		return err
+	}
+
+	configs := registry.Prune()
+	if !dryRun {
+		if err := registry.Save(); err != nil {
+			return err
+		}
+	}
+
+	referenced := make(map[string]map[string]bool)
+	ranges := make(map[string][]semver.Range)
+	reference := func(name string, version string) {
+		if version == "" {
+			return
+		}
+		name = repos.PackageName(name)
+
+		// Keep every installed version a range could resolve to
+		if !manifest.IsExactVersion(version) {
+			if expectedRange, err := manifest.ParseConstraint(version); err == nil {
+				ranges[name] = append(ranges[name], expectedRange)
+			}
+			return
+		}
+
+		if referenced[name] == nil {
+			referenced[name] = make(map[string]bool)
+		}
+		referenced[name][version] = true
+	}
+
+	for _, confPath := range configs {
+		config, err := context.LoadConfig(confPath)
+		if err != nil {
+			return fmt.Errorf(colour.Sprintf("unable to parse ^6%s^R, refusing to garbage collect "+
+				"packages it may reference: %s", confPath, err))
+		}
+
+		for name, version := range config.Use {
+			reference(name, version)
+		}
+		for _, pkg := range config.Packages {
+			reference(pkg.Name, pkg.Version)
+		}
+	}
+
+	names, err := store.InstalledPackages()
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	var reclaimed int64
+	for _, name := range names {
+		if conf, err := manifest.NewPackageManfiestConfig(name); err == nil {
+			reference(name, conf.Version)
+		}
+
+		versions, err := store.InstalledVersions(name)
+		if err != nil {
+			return err
+		}
+
+		for _, version := range versions {
+			if referenced[name][version] || inRanges(version, ranges[name]) {
+				continue
+			}
+
+			if dryRun {
+				size, err := store.DirSize(store.PackageDir(name, version))
+				if err != nil {
+					return err
+				}
+
+				reclaimed += size
+				colour.Printf("Would remove ^3%s@%s^R (%s)\n", name, version, download.FormatBytes(size))
+				continue
+			}
+
+			size, err := removePackage(name, version)
+			if err != nil {
+				return err
+			}
+
+			reclaimed += size
+			colour.Printf("^1Removed^R ^3%s@%s^R (%s)\n", name, version, download.FormatBytes(size))
+		}
+	}
+
+	if dryRun {
+		colour.Printf("Would reclaim ^2%s^R from %d project config(s)\n", download.FormatBytes(reclaimed),
+			len(configs))
+	} else {
+		colour.Printf("Reclaimed ^2%s^R from %d project config(s)\n", download.FormatBytes(reclaimed),
+			len(configs))
+	}
+
+	return nil
+}
+
+// removePackage deletes an installed package version while holding its install lock, returning the
+// number of bytes freed
+func removePackage(name string, version string) (int64, error) {
+	outDir := store.PackageDir(name, version)
+	if _, err := os.Stat(outDir); err != nil {
+		return 0, err
+	}
+
+	lock, err := store.LockPackage(name, version)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Unlock()
+
+	size, err := store.DirSize(outDir)
+	if err != nil {
+		return 0, err
+	}
+
+	return size, store.Remove(name, version)
+}
+
+func inRanges(version string, ranges []semver.Range) bool {
+	v, err := semver.Parse(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return false
+	}
+
+	for _, expectedRange := range ranges {
+		if expectedRange(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPathComponent reports whether name can be used as a single directory name in the store
+func isPathComponent(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func isInstalledVersion(installed []string, version string) bool {
+	for _, v := range installed {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
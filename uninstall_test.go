@@ -0,0 +1,173 @@
+package hvm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/josephschmitt/hvm/context"
+	"github.com/josephschmitt/hvm/manifest"
+	"github.com/josephschmitt/hvm/store"
+)
+
+func TestIsPathComponent(t *testing.T) {
+	tests := map[string]bool{
+		"node":        true,
+		"node-18.1.0": true,
+		".hidden":     true,
+		"":            false,
+		".":           false,
+		"..":          false,
+		"../node":     false,
+		"repo/node":   false,
+		`..\node`:     false,
+	}
+
+	for name, expected := range tests {
+		if actual := isPathComponent(name); actual != expected {
+			t.Errorf("isPathComponent(%q) = %t, expected %t", name, actual, expected)
+		}
+	}
+}
+
+func TestInRanges(t *testing.T) {
+	tests := []struct {
+		version     string
+		constraints []string
+		expected    bool
+	}{
+		{"1.2.0", nil, false},
+		{"1.2.0", []string{"^1.0.0"}, true},
+		{"2.0.0", []string{"^1.0.0"}, false},
+		{"2.0.0", []string{"^1.0.0", ">=2.0.0"}, true},
+		{"1.2.0", []string{"~1.1.0"}, false},
+		{"not-a-version", []string{"*"}, false},
+	}
+
+	for _, test := range tests {
+		var ranges []semver.Range
+		for _, constraint := range test.constraints {
+			r, err := manifest.ParseConstraint(constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ranges = append(ranges, r)
+		}
+
+		if actual := inRanges(test.version, ranges); actual != test.expected {
+			t.Errorf("inRanges(%s, %q) = %t, expected %t", test.version, test.constraints, actual,
+				test.expected)
+		}
+	}
+}
+
+func installedVersions(t *testing.T, name string) string {
+	t.Helper()
+
+	versions, err := store.InstalledVersions(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Join(versions, ",")
+}
+
+func TestUninstall(t *testing.T) {
+	tests := []struct {
+		name      string
+		specs     []string
+		all       bool
+		remaining string
+		fails     bool
+	}{
+		{"one version", []string{"tool@1.1.0"}, false, "1.0.0,1.2.0", false},
+		{"several versions", []string{"tool@1.0.0", "tool@1.2.0"}, false, "1.1.0", false},
+		{"repository prefix", []string{"repo/tool@1.0.0"}, false, "1.1.0,1.2.0", false},
+		{"version that isn't installed", []string{"tool@3.0.0"}, false, "1.0.0,1.1.0,1.2.0", false},
+		{"every version", []string{"tool"}, true, "", false},
+		{"no version", []string{"tool"}, false, "1.0.0,1.1.0,1.2.0", true},
+		{"version outside the store", []string{"tool@../other/1.0.0"}, false, "1.0.0,1.1.0,1.2.0",
+			false},
+		{"name outside the store", []string{"..@1.0.0"}, true, "1.0.0,1.1.0,1.2.0", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usePaths(t)
+			installVersions(t, "tool", "1.0.0", "1.1.0", "1.2.0")
+			installVersions(t, "other", "1.0.0")
+
+			err := Uninstall(&context.Context{}, test.specs, test.all)
+			if test.fails != (err != nil) {
+				t.Fatalf("expected failure=%t, got error %v", test.fails, err)
+			}
+
+			if remaining := installedVersions(t, "tool"); remaining != test.remaining {
+				t.Errorf("remaining versions %q, expected %q", remaining, test.remaining)
+			}
+			if remaining := installedVersions(t, "other"); remaining != "1.0.0" {
+				t.Errorf("other package was touched, remaining versions %q", remaining)
+			}
+		})
+	}
+}
+
+func TestGC(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		usePaths(t)
+		useRepo(t, map[string]string{"tool": toolManifest})
+		installVersions(t, "tool", "1.0.0", "1.1.0", "1.2.0", "2.0.0")
+		installVersions(t, "other", "1.0.0", "2.0.0")
+		installVersions(t, "unused", "1.0.0")
+
+		project := filepath.Join(t.TempDir(), ".hvm", "config.hcl")
+		config := `
+use = {
+  "tool": "~1.1.0"
+}
+
+package "other" {
+  version = "2.0.0"
+}
+`
+		if err := os.MkdirAll(filepath.Dir(project), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(project, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// A project that was deleted doesn't keep anything around
+		registry := &context.Registry{Configs: []string{project, "/no/such/project/config.hcl"}}
+		if err := registry.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := GC(&context.Context{}, dryRun); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]string{
+			// The range, and the manifest's default version
+			"tool":   "1.1.0,1.2.0",
+			"other":  "2.0.0",
+			"unused": "",
+		}
+		if dryRun {
+			expected = map[string]string{
+				"tool":   "1.0.0,1.1.0,1.2.0,2.0.0",
+				"other":  "1.0.0,2.0.0",
+				"unused": "1.0.0",
+			}
+		}
+
+		for name, versions := range expected {
+			if remaining := installedVersions(t, name); remaining != versions {
+				t.Errorf("dry run %t: remaining versions of %s %q, expected %q", dryRun, name,
+					remaining, versions)
+			}
+		}
+	}
+}